@@ -0,0 +1,54 @@
+package fileutils
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes fileName by passing a temp file in the same folder to
+// write and renaming it into place once it has been synced, so readers see
+// either the old content or the new, never a partial file. The temp file is
+// removed if write fails.
+func WriteFileAtomic(fileName string, perm os.FileMode, write func(w io.Writer) error) error {
+	var funcName string = "WriteFileAtomic"
+
+	if err := writeFileAtomic(fileName, perm, write); err != nil {
+		return newError(funcName, fileName, "error writing file", err)
+	}
+
+	return nil
+}
+
+func writeFileAtomic(fileName string, perm os.FileMode, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	bw := bufio.NewWriter(f)
+	err = write(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, fileName)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package fileutils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "file.txt")
+
+	err := os.WriteFile(fileName, []byte("old content"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("write failed")
+	err = WriteFileAtomic(fileName, 0640, func(w io.Writer) error {
+		if _, err := io.WriteString(w, "partial"); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected %v, got %v", failure, err)
+	}
+
+	contents, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "old content" {
+		t.Errorf("expected failed write to leave old content, got %q", contents)
+	}
+
+	err = WriteFileAtomic(fileName, 0640, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new content")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err = os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "new content" {
+		t.Errorf("expected new content, got %q", contents)
+	}
+
+	fi, err := os.Stat(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Errorf("expected mode 0640, got %v", fi.Mode().Perm())
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected temp files to be cleaned up, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,281 @@
+package fileutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const blobPerm = 0444
+
+// BlobStore keeps content addressed by its SHA-256 digest, in the same lower
+// case hex format as FileHash, under dir/objects/ab/cdef.... Identical content
+// is only stored once. Objects are read only, anything exported with a
+// hardlink shares the object and must not be modified in place.
+type BlobStore struct {
+	dir string
+}
+
+func OpenBlobStore(dir string) (*BlobStore, error) {
+	var funcName string = "OpenBlobStore"
+
+	b := &BlobStore{dir: dir}
+
+	for _, d := range []string{b.objectsDir(), b.tmpDir()} {
+		if err := MkDir(d); err != nil {
+			return nil, newError(funcName, d, "error creating folder", err)
+		}
+	}
+
+	return b, nil
+}
+
+func (b *BlobStore) Dir() string {
+	return b.dir
+}
+
+// Path returns the file that holds digest, whether or not it is present.
+func (b *BlobStore) Path(digest string) (string, error) {
+	var funcName string = "BlobStore.Path"
+
+	if !validDigest(digest) {
+		return "", newTargetError(funcName, b.dir, digest, "invalid digest", ErrInvalid)
+	}
+
+	return b.path(digest), nil
+}
+
+// Put streams r into the store and returns its digest. The content is hashed
+// as it is written to a temp file, which is then moved into place.
+func (b *BlobStore) Put(r io.Reader) (string, error) {
+	var funcName string = "BlobStore.Put"
+
+	f, err := os.CreateTemp(b.tmpDir(), "blob-*")
+	if err != nil {
+		return "", newError(funcName, b.tmpDir(), "error creating temp file", err)
+	}
+	tmp := f.Name()
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), r)
+	if err == nil {
+		err = f.Chmod(blobPerm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", newError(funcName, tmp, "error writing blob", err)
+	}
+
+	digest := hex.EncodeToString(h.Sum(nil))
+	fileName := b.path(digest)
+
+	if FileExists(fileName) {
+		os.Remove(tmp)
+		return digest, nil
+	}
+
+	if err := MkDir(filepath.Dir(fileName)); err != nil {
+		os.Remove(tmp)
+		return "", newError(funcName, filepath.Dir(fileName), "error creating folder", err)
+	}
+
+	if err := os.Rename(tmp, fileName); err != nil {
+		os.Remove(tmp)
+		return "", newTargetError(funcName, tmp, fileName, "error storing blob", err)
+	}
+
+	return digest, nil
+}
+
+// PutFile stores the content of fileName and returns its digest.
+func (b *BlobStore) PutFile(fileName string) (string, error) {
+	var funcName string = "BlobStore.PutFile"
+
+	f, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return "", newError(funcName, fileName, "error opening file", err)
+	}
+	defer f.Close()
+
+	return b.Put(f)
+}
+
+// Get opens the object for digest. A missing object matches fs.ErrNotExist.
+func (b *BlobStore) Get(digest string) (io.ReadCloser, error) {
+	var funcName string = "BlobStore.Get"
+
+	fileName, err := b.Path(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return nil, newTargetError(funcName, fileName, digest, "error opening blob", err)
+	}
+
+	return f, nil
+}
+
+func (b *BlobStore) Has(digest string) bool {
+	fileName, err := b.Path(digest)
+	if err != nil {
+		return false
+	}
+
+	return IsFile(fileName)
+}
+
+// Verify rehashes the object for digest, returning an error matching
+// ErrChecksum when the content no longer matches.
+func (b *BlobStore) Verify(digest string) error {
+	var funcName string = "BlobStore.Verify"
+
+	fileName, err := b.Path(digest)
+	if err != nil {
+		return err
+	}
+
+	actual, err := FileHash(fileName)
+	if err != nil {
+		return newTargetError(funcName, fileName, digest, "error hashing blob", err)
+	}
+
+	if actual != digest {
+		return newTargetError(funcName, fileName, actual, "", ErrChecksum)
+	}
+
+	return nil
+}
+
+// Digests lists every object in the store, sorted.
+func (b *BlobStore) Digests() ([]string, error) {
+	var funcName string = "BlobStore.Digests"
+
+	shards, err := os.ReadDir(b.objectsDir())
+	if err != nil {
+		return nil, newError(funcName, b.objectsDir(), "error reading folder", err)
+	}
+
+	var digests []string
+	for _, shard := range shards {
+		if !shard.IsDir() || len(shard.Name()) != 2 {
+			continue
+		}
+
+		shardDir := filepath.Join(b.objectsDir(), shard.Name())
+		files, err := os.ReadDir(shardDir)
+		if err != nil {
+			return nil, newError(funcName, shardDir, "error reading folder", err)
+		}
+
+		for _, f := range files {
+			digest := shard.Name() + f.Name()
+			if f.Type().IsRegular() && validDigest(digest) {
+				digests = append(digests, digest)
+			}
+		}
+	}
+
+	sort.Strings(digests)
+
+	return digests, nil
+}
+
+// GC removes every object that is not in live and returns the digests it
+// removed. Puts that finish while GC runs are kept only if they are live, so
+// callers should not store and collect at the same time.
+func (b *BlobStore) GC(live []string) ([]string, error) {
+	var funcName string = "BlobStore.GC"
+
+	keep := make(map[string]bool, len(live))
+	for _, digest := range live {
+		keep[digest] = true
+	}
+
+	digests, err := b.Digests()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, digest := range digests {
+		if keep[digest] {
+			continue
+		}
+
+		fileName := b.path(digest)
+		if err := os.Remove(fileName); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, newError(funcName, fileName, "error removing blob", err)
+		}
+		removed = append(removed, digest)
+
+		// the shard is only removed once empty
+		_ = os.Remove(filepath.Dir(fileName))
+	}
+
+	return removed, nil
+}
+
+// Export places the object for digest at dst using CopyFile, so a hardlink
+// is tried first when opts.Hardlink is set, then a reflink clone, before the
+// content is copied. Copies are made writable, hardlinks keep the read only
+// mode of the object.
+func (b *BlobStore) Export(digest, dst string, opts *CopyOptions) (CopyStrategy, error) {
+	var funcName string = "BlobStore.Export"
+
+	fileName, err := b.Path(digest)
+	if err != nil {
+		return "", err
+	}
+
+	strategy, err := CopyFile(fileName, dst, opts)
+	if err != nil {
+		return "", newTargetError(funcName, fileName, dst, "error exporting blob", err)
+	}
+
+	if strategy != CopyStrategyHardlink {
+		if err := os.Chmod(dst, dataFilePerm); err != nil {
+			return "", newError(funcName, dst, "error setting permissions", err)
+		}
+	}
+
+	return strategy, nil
+}
+
+func (b *BlobStore) objectsDir() string {
+	return filepath.Join(b.dir, "objects")
+}
+
+func (b *BlobStore) tmpDir() string {
+	return filepath.Join(b.dir, "tmp")
+}
+
+func (b *BlobStore) path(digest string) string {
+	return filepath.Join(b.objectsDir(), digest[:2], digest[2:])
+}
+
+func validDigest(digest string) bool {
+	if len(digest) != sha256.Size*2 {
+		return false
+	}
+
+	for _, c := range digest {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
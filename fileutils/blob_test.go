@@ -0,0 +1,199 @@
+package fileutils
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBlobStore(t *testing.T) {
+	dir := t.TempDir()
+
+	b, err := OpenBlobStore(filepath.Join(dir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := filepath.Join(dir, "src.txt")
+	err = os.WriteFile(src, []byte("test content"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := FileHash(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest, err := b.Put(strings.NewReader("test content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != expected {
+		t.Errorf("expected digest %v, got %v", expected, digest)
+	}
+
+	again, err := b.PutFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != digest {
+		t.Errorf("expected the same digest for the same content, got %v", again)
+	}
+
+	fileName, err := b.Path(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expectedPath := filepath.Join(b.Dir(), "objects", digest[:2], digest[2:]); fileName != expectedPath {
+		t.Errorf("expected path %v, got %v", expectedPath, fileName)
+	}
+
+	if !b.Has(digest) {
+		t.Errorf("expected store to have %v", digest)
+	}
+
+	r, err := b.Get(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "test content" {
+		t.Errorf("expected test content, got %q", contents)
+	}
+
+	if err := b.Verify(digest); err != nil {
+		t.Errorf("expected blob to verify, got %v", err)
+	}
+
+	missing := strings.Repeat("0", 64)
+	if b.Has(missing) {
+		t.Errorf("expected store not to have %v", missing)
+	}
+	if _, err := b.Get(missing); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+	if _, err := b.Get("../../src.txt"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for a bad digest, got %v", err)
+	}
+
+	tmps, err := os.ReadDir(filepath.Join(b.Dir(), "tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmps) != 0 {
+		t.Errorf("expected temp files to be cleaned up, got %d", len(tmps))
+	}
+
+	// corrupt the object behind the store's back
+	err = os.Chmod(fileName, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(fileName, []byte("changed"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Verify(digest); !errors.Is(err, ErrChecksum) {
+		t.Errorf("expected ErrChecksum, got %v", err)
+	}
+}
+
+func TestBlobStoreGC(t *testing.T) {
+	b, err := OpenBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var digests []string
+	for _, content := range []string{"a", "b", "c"} {
+		digest, err := b.Put(strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		digests = append(digests, digest)
+	}
+
+	removed, err := b.GC(digests[1:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("expected 2 blobs removed, got %v", removed)
+	}
+
+	actual, err := b.Digests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(digests[1:2], actual) {
+		t.Errorf("expected %v, got %v", digests[1:2], actual)
+	}
+}
+
+func TestBlobStoreExport(t *testing.T) {
+	dir := t.TempDir()
+
+	b, err := OpenBlobStore(filepath.Join(dir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest, err := b.Put(strings.NewReader("test content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		opts     *CopyOptions
+		hardlink bool
+		perm     os.FileMode
+	}{
+		"copy": {
+			perm: 0644,
+		},
+		"hardlink": {
+			opts:     &CopyOptions{Hardlink: true},
+			hardlink: true,
+			perm:     0444,
+		},
+	}
+
+	for name, tt := range tests {
+		dst := filepath.Join(dir, name+".txt")
+
+		strategy, err := b.Export(digest, dst, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if (strategy == CopyStrategyHardlink) != tt.hardlink {
+			t.Errorf("%s: unexpected strategy %v", name, strategy)
+		}
+
+		actual, err := FileHash(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != digest {
+			t.Errorf("%s: expected digest %v, got %v", name, digest, actual)
+		}
+
+		fi, err := os.Stat(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != tt.perm {
+			t.Errorf("%s: expected mode %v, got %v", name, tt.perm, fi.Mode().Perm())
+		}
+	}
+}
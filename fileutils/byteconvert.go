@@ -1,5 +1,7 @@
 package fileutils
 
+import "fmt"
+
 func ByteSizeConvert(fileBytes int64, units string) float64 {
 	var oneMb float64 = 1024
 
@@ -42,3 +44,14 @@ func ByteSizeConvert(fileBytes int64, units string) float64 {
 
 	return bytes
 }
+
+// byteSizeString formats fileBytes in units, falling back to whole bytes for
+// units ByteSizeConvert does not know.
+func byteSizeString(fileBytes int64, units string) string {
+	switch units {
+	case "kb", "mb", "gb", "tb", "pb", "xb", "zb":
+		return fmt.Sprintf("%.2f%v", ByteSizeConvert(fileBytes, units), units)
+	}
+
+	return fmt.Sprintf("%db", fileBytes)
+}
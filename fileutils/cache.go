@@ -0,0 +1,464 @@
+package fileutils
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rockwell-uk/go-utils/stringutils"
+)
+
+const cacheExpirySuffix = ".expires"
+
+type CacheOptions struct {
+	// default time to live for Set and Fetch, zero means entries never expire
+	TTL time.Duration
+	// evict least recently used entries once the cache holds more than
+	// MaxSize bytes, zero means no limit
+	MaxSize int64
+	// run Evict in the background every JanitorInterval, zero disables it
+	JanitorInterval time.Duration
+}
+
+// Cache is a key/value store kept in a folder, so entries survive restarts.
+// Keys are hashed with SHA-256 and stored as dir/ab/abcdef..., each written
+// atomically, with the expiry time in a .expires file alongside written just
+// before it. Reads bump the mtime of an entry, which is what Evict uses to
+// find the least recently used ones. It is safe for concurrent use, including by several processes
+// sharing the folder.
+type Cache struct {
+	dir  string
+	opts CacheOptions
+	now  func() time.Time
+
+	closeOnce sync.Once
+	done      chan struct{}
+	janitor   sync.WaitGroup
+}
+
+func NewCache(dir string, opts *CacheOptions) (*Cache, error) {
+	var funcName string = "NewCache"
+
+	c := &Cache{
+		dir:  dir,
+		now:  time.Now,
+		done: make(chan struct{}),
+	}
+	if opts != nil {
+		c.opts = *opts
+	}
+
+	if err := MkDir(dir); err != nil {
+		return nil, newError(funcName, dir, "error creating folder", err)
+	}
+
+	if c.opts.JanitorInterval > 0 {
+		c.janitor.Add(1)
+		go c.runJanitor()
+	}
+
+	return c, nil
+}
+
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Path returns the file that holds the value for key, whether or not it is
+// present.
+func (c *Cache) Path(key string) string {
+	digest := fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+
+	return filepath.Join(c.dir, digest[:2], digest)
+}
+
+// Get returns the value for key. Missing and expired entries report false.
+func (c *Cache) Get(key string) ([]byte, bool, error) {
+	var funcName string = "Cache.Get"
+
+	fileName, ok, err := c.lookup(key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	value, err := os.ReadFile(filepath.Clean(fileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, newTargetError(funcName, fileName, key, "error reading entry", err)
+	}
+
+	return value, true, nil
+}
+
+// Set stores value under key with the default TTL.
+func (c *Cache) Set(key string, value []byte) error {
+	return c.SetWithTTL(key, value, c.opts.TTL)
+}
+
+// SetWithTTL stores value under key, expiring after ttl. A zero ttl means the
+// entry never expires.
+func (c *Cache) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	var funcName string = "Cache.SetWithTTL"
+
+	err := c.store(key, ttl, func(fileName string) error {
+		return writeFileAtomic(fileName, dataFilePerm, func(w io.Writer) error {
+			_, err := w.Write(value)
+			return err
+		})
+	})
+	if err != nil {
+		return newTargetError(funcName, c.Path(key), key, "error storing entry", err)
+	}
+
+	return nil
+}
+
+// Fetch is a read-through lookup keyed by url. It returns the path to the
+// cached copy, downloading it with DownloadFileToPath when it is missing or
+// expired.
+func (c *Cache) Fetch(url string) (string, error) {
+	var funcName string = "Cache.Fetch"
+
+	fileName, ok, err := c.lookup(url)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return fileName, nil
+	}
+
+	err = c.store(url, c.opts.TTL, func(fileName string) error {
+		tmp := filepath.Join(filepath.Dir(fileName), fmt.Sprintf(".%v.%v.tmp", filepath.Base(fileName), stringutils.RandString(8)))
+
+		if err := DownloadFileToPath(url, tmp); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+
+		if err := os.Rename(tmp, fileName); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", newTargetError(funcName, c.Path(url), url, "error fetching entry", err)
+	}
+
+	return c.Path(url), nil
+}
+
+// Delete removes the entry for key. Deleting a missing key is not an error.
+func (c *Cache) Delete(key string) error {
+	var funcName string = "Cache.Delete"
+
+	if err := c.remove(c.Path(key)); err != nil {
+		return newTargetError(funcName, c.Path(key), key, "error removing entry", err)
+	}
+
+	return nil
+}
+
+// Purge removes every entry.
+func (c *Cache) Purge() error {
+	var funcName string = "Cache.Purge"
+
+	entries, err := c.entries()
+	if err != nil {
+		return newError(funcName, c.dir, "error listing entries", err)
+	}
+
+	for _, e := range entries {
+		if err := c.remove(e.fileName); err != nil {
+			return newError(funcName, e.fileName, "error removing entry", err)
+		}
+	}
+
+	return nil
+}
+
+// Evict removes expired entries and then, when MaxSize is set, the least
+// recently used entries until the cache fits. It returns the number of
+// entries removed.
+func (c *Cache) Evict() (int, error) {
+	var funcName string = "Cache.Evict"
+
+	entries, err := c.entries()
+	if err != nil {
+		return 0, newError(funcName, c.dir, "error listing entries", err)
+	}
+
+	var removed int
+	var total int64
+	now := c.now()
+
+	live := entries[:0]
+	for _, e := range entries {
+		if !e.expires.IsZero() && !now.Before(e.expires) {
+			if err := c.remove(e.fileName); err != nil {
+				return removed, newError(funcName, e.fileName, "error removing entry", err)
+			}
+			removed++
+			continue
+		}
+		total += e.size
+		live = append(live, e)
+	}
+
+	if c.opts.MaxSize <= 0 || total <= c.opts.MaxSize {
+		return removed, nil
+	}
+
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].used.Before(live[j].used)
+	})
+
+	for _, e := range live {
+		if total <= c.opts.MaxSize {
+			break
+		}
+		if err := c.remove(e.fileName); err != nil {
+			return removed, newError(funcName, e.fileName, "error removing entry", err)
+		}
+		total -= e.size
+		removed++
+	}
+
+	return removed, nil
+}
+
+// Close stops the janitor. The entries are left on disk.
+func (c *Cache) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	c.janitor.Wait()
+
+	return nil
+}
+
+// lookup returns the file for key when it is present and has not expired,
+// marking it as used. Expired entries are removed.
+func (c *Cache) lookup(key string) (string, bool, error) {
+	var funcName string = "Cache.lookup"
+
+	fileName := c.Path(key)
+
+	expires, err := readCacheExpiry(fileName)
+	if err != nil {
+		return "", false, newTargetError(funcName, fileName, key, "error reading expiry", err)
+	}
+
+	now := c.now()
+	if !expires.IsZero() && !now.Before(expires) {
+		// another process may have replaced it in the meantime, which only
+		// costs a refetch
+		_ = c.remove(fileName)
+		return "", false, nil
+	}
+
+	// best effort, a missing entry is reported below
+	err = os.Chtimes(fileName, now, now)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+
+	return fileName, true, nil
+}
+
+// store creates the shard folder and records the expiry before calling write
+// to put the entry in place. The expiry file is given the same mtime as the
+// value, so one newer than its value is from a store that never got as far
+// as the value, and readCacheExpiry treats the entry as expired. A failure
+// can then leave neither an expiry without a value, nor an old value that
+// outlives its ttl or takes on the new one. Entries without a ttl get an
+// expiry of 0 while the value is written, which is removed afterwards.
+func (c *Cache) store(key string, ttl time.Duration, write func(fileName string) error) error {
+	fileName := c.Path(key)
+
+	if err := MkDir(filepath.Dir(fileName)); err != nil {
+		return err
+	}
+
+	now := c.now()
+
+	var expires int64
+	if ttl > 0 {
+		expires = now.Add(ttl).UnixNano()
+	}
+
+	expiryFile := fileName + cacheExpirySuffix
+	err := writeFileAtomic(expiryFile, dataFilePerm, func(w io.Writer) error {
+		_, err := io.WriteString(w, strconv.FormatInt(expires, 10))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chtimes(expiryFile, now, now); err != nil {
+		_ = c.remove(fileName)
+		return err
+	}
+
+	if err := write(fileName); err != nil {
+		_ = c.remove(fileName)
+		return err
+	}
+
+	if err := os.Chtimes(fileName, now, now); err != nil {
+		_ = c.remove(fileName)
+		return err
+	}
+
+	if ttl <= 0 {
+		if err := os.Remove(expiryFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Cache) remove(fileName string) error {
+	for _, f := range []string{fileName, fileName + cacheExpirySuffix} {
+		if err := os.Remove(f); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type cacheEntry struct {
+	fileName string
+	size     int64
+	used     time.Time
+	expires  time.Time
+}
+
+func (c *Cache) entries() ([]cacheEntry, error) {
+	shards, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []cacheEntry
+	for _, shard := range shards {
+		if !shard.IsDir() || len(shard.Name()) != 2 {
+			continue
+		}
+
+		files, err := os.ReadDir(filepath.Join(c.dir, shard.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range files {
+			name := f.Name()
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+
+			// an expiry left behind by an interrupted store is garbage
+			if value := strings.TrimSuffix(name, cacheExpirySuffix); value != name {
+				if _, err := os.Lstat(filepath.Join(c.dir, shard.Name(), value)); errors.Is(err, fs.ErrNotExist) {
+					_ = os.Remove(filepath.Join(c.dir, shard.Name(), name))
+				}
+				continue
+			}
+
+			fi, err := f.Info()
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			fileName := filepath.Join(c.dir, shard.Name(), name)
+			expires, err := readCacheExpiry(fileName)
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, cacheEntry{
+				fileName: fileName,
+				size:     fi.Size(),
+				used:     fi.ModTime(),
+				expires:  expires,
+			})
+		}
+	}
+
+	return entries, nil
+}
+
+func (c *Cache) runJanitor() {
+	defer c.janitor.Done()
+
+	ticker := time.NewTicker(c.opts.JanitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			// best effort, the next tick tries again
+			_, _ = c.Evict()
+		}
+	}
+}
+
+// readCacheExpiry returns the zero time when the entry has no expiry. An
+// expiry that cannot be parsed, or that is newer than its value, counts as
+// expired, dropping the entry.
+func readCacheExpiry(fileName string) (time.Time, error) {
+	expiryFile := fileName + cacheExpirySuffix
+
+	b, err := os.ReadFile(filepath.Clean(expiryFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return time.Unix(0, 0), nil
+	}
+
+	efi, err := os.Stat(expiryFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// a missing value is left for the caller to find
+	vfi, err := os.Stat(fileName)
+	if err == nil && efi.ModTime().After(vfi.ModTime()) {
+		return time.Unix(0, 0), nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, err
+	}
+
+	if n == 0 {
+		return time.Time{}, nil
+	}
+
+	return time.Unix(0, n), nil
+}
@@ -0,0 +1,288 @@
+package fileutils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c, err := NewCache(dir, &CacheOptions{TTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.now = func() time.Time { return now }
+
+	err = c.Set("key", []byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.SetWithTTL("forever", []byte("kept"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, ok, err := c.Get("key")
+	if err != nil || !ok || string(value) != "value" {
+		t.Errorf("expected value, got %q %v %v", value, ok, err)
+	}
+
+	if _, ok, _ := c.Get("missing"); ok {
+		t.Errorf("expected missing key to miss")
+	}
+
+	// restarting keeps the entries
+	c2, err := NewCache(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2.now = func() time.Time { return now }
+	if _, ok, _ := c2.Get("key"); !ok {
+		t.Errorf("expected entry to survive a restart")
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	if _, ok, _ := c.Get("key"); ok {
+		t.Errorf("expected expired key to miss")
+	}
+	if FileExists(c.Path("key")) {
+		t.Errorf("expected expired entry to be removed")
+	}
+
+	if value, ok, _ := c.Get("forever"); !ok || string(value) != "kept" {
+		t.Errorf("expected entry without ttl to be kept, got %q %v", value, ok)
+	}
+
+	err = c.Delete("forever")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.Get("forever"); ok {
+		t.Errorf("expected deleted key to miss")
+	}
+
+	err = c.Delete("forever")
+	if err != nil {
+		t.Errorf("expected deleting a missing key to succeed, got %v", err)
+	}
+}
+
+func TestCacheEvict(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c, err := NewCache(dir, &CacheOptions{MaxSize: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		if err := c.Set(fmt.Sprintf("key%d", i), []byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+		tick := now.Add(time.Duration(i) * time.Second)
+		if err := os.Chtimes(c.Path(fmt.Sprintf("key%d", i)), tick, tick); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = c.SetWithTTL("expiring", []byte("x"), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// key0 is the oldest but was used most recently
+	now = now.Add(time.Minute)
+	if _, ok, _ := c.Get("key0"); !ok {
+		t.Fatal("expected key0 to be present")
+	}
+
+	removed, err := c.Evict()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 3 {
+		t.Errorf("expected 3 entries removed, got %d", removed)
+	}
+
+	for key, expected := range map[string]bool{"key0": true, "key1": false, "key2": false, "key3": true, "expiring": false} {
+		if actual := FileExists(c.Path(key)); actual != expected {
+			t.Errorf("%s: expected present %v, got %v", key, expected, actual)
+		}
+	}
+
+	err = c.Purge()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := c.entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected empty cache after purge, got %d entries", len(entries))
+	}
+}
+
+func TestCacheJanitor(t *testing.T) {
+	c, err := NewCache(t.TempDir(), &CacheOptions{TTL: time.Millisecond, JanitorInterval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.Set("key", []byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for FileExists(c.Path("key")) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected janitor to remove expired entry")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCacheFetch(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "downloaded")
+	}))
+	defer server.Close()
+
+	c, err := NewCache(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		fileName, err := c.Fetch(server.URL + "/file")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contents, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(contents) != "downloaded" {
+			t.Errorf("expected downloaded, got %q", contents)
+		}
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+
+	_, err = c.Fetch(server.URL + "/missing")
+	if err == nil {
+		t.Errorf("expected error fetching a missing url, got nil")
+	}
+
+	files, err := filepath.Glob(filepath.Join(c.Dir(), "*", ".*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("expected temp files to be cleaned up, got %v", files)
+	}
+}
+
+func TestCacheBrokenExpiry(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	c, err := NewCache(t.TempDir(), &CacheOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// a failed store leaves no expiry behind
+	if _, err := c.Fetch(server.URL + "/missing"); err == nil {
+		t.Errorf("expected error fetching a missing url, got nil")
+	}
+	if FileExists(c.Path(server.URL+"/missing") + cacheExpirySuffix) {
+		t.Errorf("expected no expiry for a failed fetch")
+	}
+
+	if err := c.Set("live", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("garbled", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetWithTTL("interrupted", []byte("old"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// a store that wrote its expiry but died before the value must not let
+	// the old value live on, whatever the expiry says
+	interrupted := c.Path("interrupted") + cacheExpirySuffix
+	expires := strconv.FormatInt(time.Now().Add(time.Hour).UnixNano(), 10)
+	if err := os.WriteFile(interrupted, []byte(expires), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(interrupted, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	orphan := c.Path("orphan") + cacheExpirySuffix
+	if err := MkDir(filepath.Dir(orphan)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(orphan, []byte("not a number"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(c.Path("garbled")+cacheExpirySuffix, []byte("not a number"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := c.Evict()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("expected the garbled and interrupted entries to be removed, got %d", removed)
+	}
+
+	for fileName, expected := range map[string]bool{
+		c.Path("live"):        true,
+		c.Path("garbled"):     false,
+		c.Path("interrupted"): false,
+		interrupted:           false,
+		orphan:                false,
+	} {
+		if actual := FileExists(fileName); actual != expected {
+			t.Errorf("%s: expected present %v, got %v", fileName, expected, actual)
+		}
+	}
+
+	if _, ok, err := c.Get("garbled"); ok || err != nil {
+		t.Errorf("expected garbled entry to be gone, got %v %v", ok, err)
+	}
+}
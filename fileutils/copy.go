@@ -0,0 +1,145 @@
+package fileutils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+type CopyStrategy string
+
+const (
+	CopyStrategyHardlink      CopyStrategy = "hardlink"
+	CopyStrategyClone         CopyStrategy = "clone"
+	CopyStrategyCopyFileRange CopyStrategy = "copy_file_range"
+	CopyStrategySparse        CopyStrategy = "sparse"
+	CopyStrategyStream        CopyStrategy = "stream"
+)
+
+type CopyOptions struct {
+	// try a hardlink before copying, only used when dst does not exist
+	Hardlink bool
+	// copy extended attributes onto dst
+	KeepXattrs bool
+}
+
+// CopyFile copies the regular file src to dst, keeping its permissions, and
+// reports which strategy did the work. Strategies are tried cheapest first,
+// see copyContents for the platform specific order, and an io.Copy is always
+// the final fallback. An existing dst is truncated, unless it is src itself
+// in which case ErrInvalid is returned.
+func CopyFile(src, dst string, opts *CopyOptions) (CopyStrategy, error) {
+	var funcName string = "CopyFile"
+
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", newError(funcName, src, "error opening file", err)
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return "", newError(funcName, src, "error getting file info", err)
+	}
+
+	if !fi.Mode().IsRegular() {
+		return "", newError(funcName, src, "", ErrNotRegular)
+	}
+
+	// truncating dst would destroy src when they are the same file, which
+	// includes dst being a link to src
+	if dfi, err := os.Stat(dst); err == nil && os.SameFile(fi, dfi) {
+		return "", newTargetError(funcName, src, dst, "source and destination are the same file", ErrInvalid)
+	}
+
+	if opts.Hardlink && os.Link(src, dst) == nil {
+		return CopyStrategyHardlink, nil
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode().Perm())
+	if err != nil {
+		return "", newError(funcName, dst, "error creating file", err)
+	}
+
+	strategy, err := copyContents(out, in, fi)
+	if err != nil {
+		out.Close()
+		return "", newTargetError(funcName, src, dst, "error copying file", err)
+	}
+
+	// O_CREATE does not touch the mode of an existing file
+	if err := out.Chmod(fi.Mode().Perm()); err != nil {
+		out.Close()
+		return "", newError(funcName, dst, "error setting permissions", err)
+	}
+
+	if err := out.Close(); err != nil {
+		return "", newError(funcName, dst, "error closing file", err)
+	}
+
+	if opts.KeepXattrs {
+		if err := CopyXattrs(src, dst); err != nil {
+			return "", newTargetError(funcName, src, dst, "error copying xattrs", err)
+		}
+	}
+
+	return strategy, nil
+}
+
+// MoveFile renames src to dst, falling back to CopyFile and removing src when
+// the two paths are on different filesystems.
+func MoveFile(src, dst string, opts *CopyOptions) error {
+	var funcName string = "MoveFile"
+
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, syscall.EXDEV) {
+		return newTargetError(funcName, src, dst, "error renaming file", err)
+	}
+
+	if _, err := CopyFile(src, dst, opts); err != nil {
+		return newTargetError(funcName, src, dst, "error copying file", err)
+	}
+
+	if err := os.Remove(src); err != nil {
+		return newError(funcName, src, "error removing file", err)
+	}
+
+	return nil
+}
+
+func copyStream(dst, src *os.File) (CopyStrategy, error) {
+	if err := resetCopy(dst, src); err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", err
+	}
+
+	return CopyStrategyStream, nil
+}
+
+// resetCopy rewinds both files so a failed strategy leaves nothing behind for
+// the next one.
+func resetCopy(dst, src *os.File) error {
+	if err := dst.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := dst.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	return nil
+}
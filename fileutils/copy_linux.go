@@ -0,0 +1,175 @@
+package fileutils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	seekData = 3
+	seekHole = 4
+)
+
+// FICLONE and copy_file_range are missing from the frozen syscall package.
+var (
+	ioctlFiclone      uintptr = 0x40049409
+	sysCopyFileRange  uintptr
+	copyFileRangeSize int64 = 1 << 30
+)
+
+func init() {
+	switch runtime.GOARCH {
+	case "amd64":
+		sysCopyFileRange = 326
+	case "386":
+		sysCopyFileRange = 377
+	case "arm":
+		sysCopyFileRange = 391
+	case "arm64", "riscv64", "loong64":
+		sysCopyFileRange = 285
+	case "ppc64", "ppc64le":
+		sysCopyFileRange = 379
+		ioctlFiclone = 0x80049409
+	case "s390x":
+		sysCopyFileRange = 375
+	case "mips", "mipsle":
+		sysCopyFileRange = 4360
+		ioctlFiclone = 0x80049409
+	case "mips64", "mips64le":
+		sysCopyFileRange = 5320
+		ioctlFiclone = 0x80049409
+	}
+}
+
+// copyContents tries a copy-on-write clone, then copy_file_range, then an io
+// copy. Sources with holes skip copy_file_range, which fills holes on most
+// filesystems, and use a SEEK_DATA/SEEK_HOLE copy instead.
+func copyContents(dst, src *os.File, fi os.FileInfo) (CopyStrategy, error) {
+	if cloneFile(dst, src) == nil {
+		return CopyStrategyClone, nil
+	}
+
+	if isSparse(fi) {
+		if err := copySparse(dst, src, fi.Size()); err == nil {
+			return CopyStrategySparse, nil
+		}
+	} else if sysCopyFileRange != 0 {
+		if err := copyRange(dst, src, 0, 0, fi.Size()); err == nil {
+			return CopyStrategyCopyFileRange, nil
+		}
+	}
+
+	return copyStream(dst, src)
+}
+
+func cloneFile(dst, src *os.File) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dst.Fd(), ioctlFiclone, src.Fd())
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func isSparse(fi os.FileInfo) bool {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+
+	return st.Blocks*512 < fi.Size()
+}
+
+// copyRange copies n bytes from src at srcOff to dst at dstOff, in kernel
+// where possible and with pread/pwrite when copy_file_range is unavailable.
+func copyRange(dst, src *os.File, srcOff, dstOff, n int64) error {
+	if sysCopyFileRange == 0 {
+		return copyRangeIO(dst, src, srcOff, dstOff, n)
+	}
+
+	for n > 0 {
+		size := n
+		if size > copyFileRangeSize {
+			size = copyFileRangeSize
+		}
+
+		r, _, errno := syscall.Syscall6(sysCopyFileRange,
+			src.Fd(), uintptr(unsafe.Pointer(&srcOff)),
+			dst.Fd(), uintptr(unsafe.Pointer(&dstOff)),
+			uintptr(size), 0)
+		if errno != 0 {
+			return errno
+		}
+		if r == 0 {
+			// source shrank underneath us
+			return io.ErrUnexpectedEOF
+		}
+
+		n -= int64(r)
+	}
+
+	return nil
+}
+
+func copyRangeIO(dst, src *os.File, srcOff, dstOff, n int64) error {
+	buf := make([]byte, 128*1024)
+	r := io.NewSectionReader(src, srcOff, n)
+
+	for {
+		c, err := r.Read(buf)
+		if c > 0 {
+			if _, werr := dst.WriteAt(buf[:c], dstOff); werr != nil {
+				return werr
+			}
+			dstOff += int64(c)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// copySparse copies only the data segments of src, then extends dst to size
+// so trailing holes are kept.
+func copySparse(dst, src *os.File, size int64) error {
+	if err := resetCopy(dst, src); err != nil {
+		return err
+	}
+
+	var pos int64
+	for pos < size {
+		start, err := src.Seek(pos, seekData)
+		if errors.Is(err, syscall.ENXIO) {
+			// no data after pos
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		end, err := src.Seek(start, seekHole)
+		if err != nil {
+			return err
+		}
+
+		if err := copyRange(dst, src, start, start, end-start); err != nil {
+			if sysCopyFileRange == 0 {
+				return err
+			}
+			if err := copyRangeIO(dst, src, start, start, end-start); err != nil {
+				return err
+			}
+		}
+
+		pos = end
+	}
+
+	return dst.Truncate(size)
+}
@@ -0,0 +1,172 @@
+package fileutils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCopyFileSparse(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "sparse.img")
+	dst := filepath.Join(dir, "sparse-copy.img")
+
+	var size int64 = 16 << 20
+
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.Truncate(size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, off := range []int64{0, 8 << 20} {
+		if _, err := f.WriteAt([]byte("data"), off); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	fi, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isSparse(fi) {
+		t.Skip("filesystem does not support sparse files")
+	}
+
+	strategy, err := CopyFile(src, dst, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strategy != CopyStrategyClone && strategy != CopyStrategySparse {
+		t.Errorf("expected clone or sparse strategy, got %v", strategy)
+	}
+
+	expected, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("expected copied contents to match source")
+	}
+
+	var st syscall.Stat_t
+	err = syscall.Stat(dst, &st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if st.Size != size {
+		t.Errorf("expected size %v, got %v", size, st.Size)
+	}
+
+	if st.Blocks*512 >= size {
+		t.Errorf("expected copy to keep holes, %v blocks allocated", st.Blocks)
+	}
+}
+
+func TestCopyFileRange(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "dense.bin")
+	dst := filepath.Join(dir, "dense-copy.bin")
+
+	content := bytes.Repeat([]byte("0123456789"), 100000)
+
+	err := os.WriteFile(src, content, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strategy, err := CopyFile(src, dst, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strategy == CopyStrategySparse || strategy == CopyStrategyHardlink {
+		t.Errorf("unexpected strategy %v for dense file", strategy)
+	}
+
+	actual, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(content, actual) {
+		t.Errorf("expected copied contents to match source")
+	}
+}
+
+func TestCopyFileKeepXattrs(t *testing.T) {
+	dir := testTree(t, fstest.MapFS{"xattr.txt": {}})
+	src := filepath.Join(dir, "xattr.txt")
+	dst := filepath.Join(dir, "copy.txt")
+	skipUnlessXattrs(t, src)
+
+	err := SetXattr(src, "user.stage", []byte("processed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = CopyFile(src, dst, &CopyOptions{KeepXattrs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := GetXattr(dst, "user.stage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(actual) != "processed" {
+		t.Errorf("expected xattr processed, got %q", actual)
+	}
+}
+
+func TestCopyRangeIO(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("0123456789")
+
+	src, err := os.Create(filepath.Join(dir, "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	dst, err := os.Create(filepath.Join(dir, "dst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	_, err = src.Write(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = copyRangeIO(dst, src, 2, 4, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := os.ReadFile(dst.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal([]byte("\x00\x00\x00\x0023456"), actual) {
+		t.Errorf("unexpected contents %q", actual)
+	}
+}
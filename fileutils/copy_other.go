@@ -0,0 +1,11 @@
+//go:build !linux
+
+package fileutils
+
+import (
+	"os"
+)
+
+func copyContents(dst, src *os.File, fi os.FileInfo) (CopyStrategy, error) {
+	return copyStream(dst, src)
+}
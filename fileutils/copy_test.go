@@ -0,0 +1,151 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	testContent := "test content"
+
+	err := os.WriteFile(src, []byte(testContent), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		dst      string
+		opts     *CopyOptions
+		strategy CopyStrategy
+	}{
+		"default": {
+			dst: filepath.Join(dir, "copy.txt"),
+		},
+		"overwrite": {
+			dst: filepath.Join(dir, "copy.txt"),
+		},
+		"hardlink": {
+			dst:      filepath.Join(dir, "link.txt"),
+			opts:     &CopyOptions{Hardlink: true},
+			strategy: CopyStrategyHardlink,
+		},
+	}
+
+	for _, name := range []string{"default", "overwrite", "hardlink"} {
+		tt := tests[name]
+
+		strategy, err := CopyFile(src, tt.dst, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if tt.strategy != "" && tt.strategy != strategy {
+			t.Errorf("%s: expected strategy %v, got %v", name, tt.strategy, strategy)
+		}
+
+		contents, err := os.ReadFile(tt.dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(contents) != testContent {
+			t.Errorf("%s: expected contents to equal %v [%v]", name, testContent, string(contents))
+		}
+
+		fi, err := os.Stat(tt.dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fi.Mode().Perm() != 0600 {
+			t.Errorf("%s: expected mode 0600, got %v", name, fi.Mode().Perm())
+		}
+	}
+
+	_, err = CopyFile(dir, filepath.Join(dir, "folder"), nil)
+	if err == nil {
+		t.Errorf("expected error copying a folder, got nil")
+	}
+}
+
+func TestCopyFileSameFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	testContent := "precious data"
+
+	err := os.WriteFile(src, []byte(testContent), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	symlink := filepath.Join(dir, "symlink.txt")
+	if err := os.Symlink(src, symlink); err != nil {
+		t.Fatal(err)
+	}
+
+	hardlink := filepath.Join(dir, "hardlink.txt")
+	if err := os.Link(src, hardlink); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		dst string
+	}{
+		"same path": {
+			dst: src,
+		},
+		"unclean path": {
+			dst: filepath.Join(dir, ".", "src.txt"),
+		},
+		"symlink to src": {
+			dst: symlink,
+		},
+		"hardlink to src": {
+			dst: hardlink,
+		},
+	}
+
+	for name, tt := range tests {
+		_, err := CopyFile(src, tt.dst, nil)
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected %v, got %v", name, ErrInvalid, err)
+		}
+
+		contents, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(contents) != testContent {
+			t.Errorf("%s: expected contents to equal %v [%v]", name, testContent, string(contents))
+		}
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	dst := filepath.Join(dir, "dst.txt")
+
+	err := WriteFile(src, "test content")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = MoveFile(src, dst, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if FileExists(src) {
+		t.Errorf("expected %v to be removed", src)
+	}
+
+	if !FileExists(dst) {
+		t.Errorf("expected %v to exist", dst)
+	}
+}
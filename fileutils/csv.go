@@ -0,0 +1,297 @@
+package fileutils
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// CSV columns are matched to struct fields by the `csv:"name"` tag, falling
+// back to the field name. Fields tagged `csv:"-"` and unexported fields are
+// ignored. Strings, bools, numbers, time.Time (RFC 3339) and types that
+// implement encoding.TextMarshaler and encoding.TextUnmarshaler are supported.
+const csvTag = "csv"
+
+type csvField struct {
+	name  string
+	index int
+}
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// CSVReader decodes the rows of a CSV file with a header line into T, which
+// must be a struct.
+type CSVReader[T any] struct {
+	fileName string
+	f        *os.File
+	r        *csv.Reader
+	// struct field index for each column, -1 when no field matches
+	columns []int
+}
+
+func NewCSVReader[T any](fileName string) (*CSVReader[T], error) {
+	var funcName string = "NewCSVReader"
+
+	fields, err := csvFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, newError(funcName, fileName, "", err)
+	}
+
+	f, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return nil, newError(funcName, fileName, "error opening file", err)
+	}
+
+	r := csv.NewReader(f)
+	r.ReuseRecord = true
+
+	header, err := r.Read()
+	if err != nil {
+		f.Close()
+		return nil, newError(funcName, fileName, "error reading header", err)
+	}
+
+	columns := make([]int, len(header))
+	for i, name := range header {
+		columns[i] = -1
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		for _, field := range fields {
+			if field.name == name {
+				columns[i] = field.index
+				break
+			}
+		}
+	}
+
+	return &CSVReader[T]{
+		fileName: fileName,
+		f:        f,
+		r:        r,
+		columns:  columns,
+	}, nil
+}
+
+// Next returns the next row, or io.EOF once the file is exhausted.
+func (c *CSVReader[T]) Next() (T, error) {
+	var funcName string = "CSVReader.Next"
+
+	var v T
+
+	record, err := c.r.Read()
+	if errors.Is(err, io.EOF) {
+		return v, io.EOF
+	}
+	if err != nil {
+		return v, newError(funcName, c.fileName, "error reading row", err)
+	}
+
+	rv := reflect.ValueOf(&v).Elem()
+	for i, value := range record {
+		if i >= len(c.columns) || c.columns[i] < 0 {
+			continue
+		}
+
+		if err := setCSVValue(rv.Field(c.columns[i]), value); err != nil {
+			line, _ := c.r.FieldPos(i)
+			return v, newTargetError(funcName, c.fileName, lineTarget(line), "error decoding column "+strconv.Itoa(i+1), err)
+		}
+	}
+
+	return v, nil
+}
+
+func (c *CSVReader[T]) Close() error {
+	return c.f.Close()
+}
+
+// ReadCSV decodes every row of fileName into a slice of T.
+func ReadCSV[T any](fileName string) ([]T, error) {
+	r, err := NewCSVReader[T](fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var records []T
+	for {
+		v, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, v)
+	}
+}
+
+// WriteCSV atomically replaces fileName with a header line followed by one row
+// per record.
+func WriteCSV[T any](fileName string, records []T) error {
+	var funcName string = "WriteCSV"
+
+	fields, err := csvFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return newError(funcName, fileName, "", err)
+	}
+
+	err = writeFileAtomic(fileName, dataFilePerm, func(w io.Writer) error {
+		cw := csv.NewWriter(w)
+
+		row := make([]string, len(fields))
+		for i, field := range fields {
+			row[i] = field.name
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+
+		for i := range records {
+			rv := reflect.ValueOf(&records[i]).Elem()
+			for j, field := range fields {
+				s, err := csvValue(rv.Field(field.index))
+				if err != nil {
+					return fmt.Errorf("record %d, column %v: %w", i, field.name, err)
+				}
+				row[j] = s
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+
+		cw.Flush()
+		return cw.Error()
+	})
+	if err != nil {
+		return newError(funcName, fileName, "error writing file", err)
+	}
+
+	return nil
+}
+
+func csvFields(t reflect.Type) ([]csvField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%v is not a struct: %w", t, ErrInvalid)
+	}
+
+	var fields []csvField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := sf.Name
+		if tag, ok := sf.Tag.Lookup(csvTag); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+
+		fields = append(fields, csvField{name: name, index: i})
+	}
+
+	return fields, nil
+}
+
+func setCSVValue(v reflect.Value, s string) error {
+	if s == "" && v.Kind() != reflect.String {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setCSVValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %v: %w", v.Type(), ErrInvalid)
+	}
+
+	return nil
+}
+
+// csvValue formats v for a cell. A nil pointer is an empty cell.
+func csvValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textMarshalerType) {
+		b, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+
+	return "", fmt.Errorf("unsupported field type %v: %w", v.Type(), ErrInvalid)
+}
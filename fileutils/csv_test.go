@@ -0,0 +1,149 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testRow struct {
+	Name    string    `csv:"name"`
+	Count   int       `csv:"count"`
+	Ratio   float64   `csv:"ratio"`
+	Enabled bool      `csv:"enabled"`
+	Updated time.Time `csv:"updated"`
+	Skipped string    `csv:"-"`
+	Plain   uint16
+	private string
+}
+
+func TestCSV(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "data.csv")
+
+	updated := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	expected := []testRow{
+		{Name: "a", Count: 1, Ratio: 0.5, Enabled: true, Updated: updated, Plain: 7},
+		{Name: "b, with comma", Count: -2, Ratio: 1e-9},
+	}
+
+	err := WriteCSV(fileName, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := "name,count,ratio,enabled,updated,Plain\n"
+	if string(contents[:len(header)]) != header {
+		t.Errorf("expected header %q, got %q", header, contents[:len(header)])
+	}
+
+	actual, err := ReadCSV[testRow](fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestCSVPointers(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "data.csv")
+
+	type row struct {
+		Name    string     `csv:"name"`
+		Updated *time.Time `csv:"updated"`
+		Count   *int       `csv:"count"`
+	}
+
+	updated := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	count := 3
+	expected := []row{
+		{Name: "set", Updated: &updated, Count: &count},
+		{Name: "nil"},
+	}
+
+	err := WriteCSV(fileName, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(contents), "\nnil,,\n") {
+		t.Errorf("expected empty cells for nil pointers, got %q", contents)
+	}
+
+	actual, err := ReadCSV[row](fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := map[string]struct {
+		contents string
+		expected []testRow
+		err      bool
+	}{
+		"reordered and unknown columns": {
+			contents: "\ufeffcount,extra,name\n3,x,c\n,y,d\n",
+			expected: []testRow{{Name: "c", Count: 3}, {Name: "d"}},
+		},
+		"header only": {
+			contents: "name,count\n",
+		},
+		"bad number": {
+			contents: "name,count\na,1\nb,two\n",
+			err:      true,
+		},
+		"empty": {
+			contents: "",
+			err:      true,
+		},
+	}
+
+	for name, tt := range tests {
+		fileName := filepath.Join(dir, name+".csv")
+
+		err := os.WriteFile(fileName, []byte(tt.contents), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := ReadCSV[testRow](fileName)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%s: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+
+	_, err := ReadCSV[string](filepath.Join(dir, "header only.csv"))
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for a non struct type, got %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package fileutils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const diffContext = 3
+
+type diffEdit struct {
+	// ' ' for a line in both, '-' for a line only in a and '+' only in b
+	op   byte
+	line string
+}
+
+// unifiedDiff returns the changes from a to b in unified diff format, or an
+// empty string when they are the same.
+func unifiedDiff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %v\n+++ %v\n", fromName, toName)
+
+	// line numbers, 0 based, of each edit in a and b
+	aLines := make([]int, len(edits)+1)
+	bLines := make([]int, len(edits)+1)
+	for i, e := range edits {
+		aLines[i+1], bLines[i+1] = aLines[i], bLines[i]
+		if e.op != '+' {
+			aLines[i+1]++
+		}
+		if e.op != '-' {
+			bLines[i+1]++
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+
+		// extend the hunk while the next change is close enough to share
+		// context
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].op != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		stop := end + diffContext
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+
+		fmt.Fprintf(&sb, "@@ -%v +%v @@\n",
+			hunkRange(aLines[start], aLines[stop]-aLines[start]),
+			hunkRange(bLines[start], bLines[stop]-bLines[start]))
+
+		for _, e := range edits[start:stop] {
+			sb.WriteByte(e.op)
+			sb.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = stop
+	}
+
+	return sb.String()
+}
+
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+// splitLines splits s after each newline, keeping the newlines.
+func splitLines(s string) []string {
+	var lines []string
+	for s != "" {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+
+	return lines
+}
+
+// diffMaxWork bounds the comparisons spent looking for one middle snake,
+// past it the lines being compared are reported as replaced wholesale. The
+// diff is then valid but no longer the shortest.
+const diffMaxWork = 1 << 25
+
+// diffLines returns an edit script from a to b, the shortest one unless the
+// inputs are large and very different. Runs of changes list the removed lines
+// before the added ones.
+func diffLines(a, b []string) []diffEdit {
+	d := newDiffer(len(a), len(b))
+	d.diff(a, b)
+
+	edits := d.edits
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(edits) && edits[j].op != ' ' {
+			j++
+		}
+		sort.SliceStable(edits[i:j], func(x, y int) bool {
+			return edits[i+x].op == '-' && edits[i+y].op == '+'
+		})
+		i = j
+	}
+
+	return edits
+}
+
+// differ is Myers' linear space diff, recursing on each side of the middle
+// snake of the shortest edit path, so memory stays proportional to the input.
+type differ struct {
+	edits  []diffEdit
+	vf, vb []int
+	off    int
+}
+
+func newDiffer(n, m int) *differ {
+	max := (n+m+1)/2 + 1
+
+	return &differ{
+		edits: make([]diffEdit, 0, n+m),
+		vf:    make([]int, 2*max+1),
+		vb:    make([]int, 2*max+1),
+		off:   max,
+	}
+}
+
+func (d *differ) diff(a, b []string) {
+	var prefix, suffix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	d.add(' ', a[:prefix])
+
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	switch {
+	case len(ma) == 0:
+		d.add('+', mb)
+	case len(mb) == 0:
+		d.add('-', ma)
+	default:
+		x, y, u, v, ok := d.middleSnake(ma, mb)
+		if !ok {
+			d.add('-', ma)
+			d.add('+', mb)
+			break
+		}
+
+		d.diff(ma[:x], mb[:y])
+		d.add(' ', ma[x:u])
+		d.diff(ma[u:], mb[v:])
+	}
+
+	d.add(' ', a[len(a)-suffix:])
+}
+
+func (d *differ) add(op byte, lines []string) {
+	for _, l := range lines {
+		d.edits = append(d.edits, diffEdit{op, l})
+	}
+}
+
+// middleSnake searches forwards from the start and backwards from the end of
+// a and b until the two paths overlap, and returns the snake where they meet,
+// from (x, y) to (u, v). The backward search works on the reversed inputs,
+// diagonal k there being diagonal delta-k going forwards.
+func (d *differ) middleSnake(a, b []string) (x, y, u, v int, ok bool) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	vf, vb, off := d.vf, d.vb, d.off
+
+	vf[off+1], vb[off+1] = 0, 0
+
+	for cost := 0; cost <= (n+m+1)/2; cost++ {
+		if cost*(n+m) > diffMaxWork {
+			return 0, 0, 0, 0, false
+		}
+
+		for k := -cost; k <= cost; k += 2 {
+			var x0 int
+			if k == -cost || (k != cost && vf[off+k-1] < vf[off+k+1]) {
+				x0 = vf[off+k+1]
+			} else {
+				x0 = vf[off+k-1] + 1
+			}
+			y0 := x0 - k
+
+			x, y := x0, y0
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			vf[off+k] = x
+
+			if odd && delta-k >= -(cost-1) && delta-k <= cost-1 && x+vb[off+delta-k] >= n {
+				return x0, y0, x, y, true
+			}
+		}
+
+		for k := -cost; k <= cost; k += 2 {
+			var x0 int
+			if k == -cost || (k != cost && vb[off+k-1] < vb[off+k+1]) {
+				x0 = vb[off+k+1]
+			} else {
+				x0 = vb[off+k-1] + 1
+			}
+			y0 := x0 - k
+
+			x, y := x0, y0
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			vb[off+k] = x
+
+			if !odd && delta-k >= -cost && delta-k <= cost && x+vf[off+delta-k] >= n {
+				return n - x, m - y, n - x0, m - y0, true
+			}
+		}
+	}
+
+	return 0, 0, 0, 0, false
+}
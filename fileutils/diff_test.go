@@ -0,0 +1,147 @@
+package fileutils
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := map[string]struct {
+		a, b     string
+		expected string
+	}{
+		"same": {
+			a: "one\ntwo\n",
+			b: "one\ntwo\n",
+		},
+		"change": {
+			a: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b: "1\n2\n3\n4\nfive\n6\n7\n8\n",
+			expected: "--- a\n+++ b\n" +
+				"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		"separate hunks": {
+			a: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b: "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			expected: "--- a\n+++ b\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		"merged hunks": {
+			a: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b: "one\n2\n3\n4\n5\n6\n7\neight\n",
+			expected: "--- a\n+++ b\n" +
+				"@@ -1,8 +1,8 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n",
+		},
+		"insert and delete": {
+			a: "a\nb\nc\n",
+			b: "a\nc\nd\n",
+			expected: "--- a\n+++ b\n" +
+				"@@ -1,3 +1,3 @@\n a\n-b\n c\n+d\n",
+		},
+		"from empty": {
+			a: "",
+			b: "new\n",
+			expected: "--- a\n+++ b\n" +
+				"@@ -0,0 +1 @@\n+new\n",
+		},
+		"no final newline": {
+			a: "a\nb",
+			b: "a\nc",
+			expected: "--- a\n+++ b\n" +
+				"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+		},
+	}
+
+	for name, tt := range tests {
+		actual := unifiedDiff("a", "b", tt.a, tt.b)
+		if actual != tt.expected {
+			t.Errorf("%s: expected\n%v\ngot\n%v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	a := splitLines(strings.Repeat("x\ny\nz\n", 20))
+	b := splitLines(strings.Repeat("x\nz\nw\n", 20))
+
+	edits := diffLines(a, b)
+
+	var from, to []string
+	var changes int
+	for _, e := range edits {
+		if e.op != '+' {
+			from = append(from, e.line)
+		}
+		if e.op != '-' {
+			to = append(to, e.line)
+		}
+		if e.op != ' ' {
+			changes++
+		}
+	}
+
+	if strings.Join(from, "") != strings.Join(a, "") || strings.Join(to, "") != strings.Join(b, "") {
+		t.Errorf("expected edits to reproduce both inputs")
+	}
+
+	if changes != 40 {
+		t.Errorf("expected a shortest script of 40 changes, got %d", changes)
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	tests := map[string]struct {
+		lines   int
+		changes int
+	}{
+		// small enough to find the shortest script
+		"shortest": {
+			lines:   4000,
+			changes: 4000,
+		},
+		// too different to search, all but the shared last line are reported
+		// as replaced wholesale
+		"capped": {
+			lines:   100000,
+			changes: 199998,
+		},
+	}
+
+	for name, tt := range tests {
+		var a, b []string
+		for i := 0; i < tt.lines; i++ {
+			line := "line " + strconv.Itoa(i) + "\n"
+			a = append(a, line)
+			if i%2 == 0 {
+				line = "changed " + strconv.Itoa(i) + "\n"
+			}
+			b = append(b, line)
+		}
+
+		edits := diffLines(a, b)
+
+		var from, to []string
+		var changes int
+		for _, e := range edits {
+			if e.op != '+' {
+				from = append(from, e.line)
+			}
+			if e.op != '-' {
+				to = append(to, e.line)
+			}
+			if e.op != ' ' {
+				changes++
+			}
+		}
+
+		if strings.Join(from, "") != strings.Join(a, "") || strings.Join(to, "") != strings.Join(b, "") {
+			t.Errorf("%s: expected edits to reproduce both inputs", name)
+		}
+
+		if changes != tt.changes {
+			t.Errorf("%s: expected %d changes, got %d", name, tt.changes, changes)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package fileutils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Encrypted streams start with a header, authenticated along with every
+// frame:
+//
+//	magic "FUEN" | version (1) | chunk size (uint32) | salt (32) | nonce prefix (7) | key ID length (1) | key ID
+//
+// followed by the plaintext in chunks, each sealed with AES-256-GCM into its
+// own frame. Every stream is sealed with its own key, derived from the
+// EncryptionKey and the random salt with HKDF-SHA256, so nonces only have to
+// be unique within a stream and one EncryptionKey can protect any number of
+// files. The nonce of a frame is the prefix, a uint32 frame counter and a
+// byte that is set only on the last frame, so reordered, dropped or truncated
+// frames all fail authentication.
+const (
+	encryptMagic        = "FUEN"
+	encryptVersion      = 1
+	encryptChunkSize    = 64 * 1024
+	encryptMaxChunkSize = 16 * 1024 * 1024
+	encryptSaltSize     = 32
+	encryptPrefixSize   = 7
+	encryptFilePerm     = 0600
+	encryptKeyInfo      = "fileutils encrypt v1"
+
+	KeySize = 32
+)
+
+type EncryptionKey struct {
+	// stored in the header so the right key can be picked to decrypt
+	ID  string `json:"id"`
+	Key []byte `json:"key"`
+}
+
+// NewEncryptionKey returns a random AES-256 key.
+func NewEncryptionKey(id string) (EncryptionKey, error) {
+	var funcName string = "NewEncryptionKey"
+
+	k := EncryptionKey{ID: id, Key: make([]byte, KeySize)}
+
+	if len(id) > 255 {
+		return EncryptionKey{}, newTargetError(funcName, "", id, "key id too long", ErrInvalid)
+	}
+
+	if _, err := rand.Read(k.Key); err != nil {
+		return EncryptionKey{}, newError(funcName, "", "error generating key", err)
+	}
+
+	return k, nil
+}
+
+// ReadKeyFile loads a key saved by WriteKeyFile.
+func ReadKeyFile(fileName string) (EncryptionKey, error) {
+	var funcName string = "ReadKeyFile"
+
+	var k EncryptionKey
+
+	b, err := os.ReadFile(filepath.Clean(fileName))
+	if err != nil {
+		return k, newError(funcName, fileName, "error reading file", err)
+	}
+
+	if err := json.Unmarshal(b, &k); err != nil {
+		return EncryptionKey{}, newError(funcName, fileName, "error decoding key", err)
+	}
+
+	if len(k.Key) != KeySize || len(k.ID) > 255 {
+		return EncryptionKey{}, newError(funcName, fileName, "invalid key", ErrInvalid)
+	}
+
+	return k, nil
+}
+
+// WriteKeyFile atomically saves key to fileName, readable only by its owner.
+func WriteKeyFile(fileName string, key EncryptionKey) error {
+	var funcName string = "WriteKeyFile"
+
+	b, err := json.Marshal(key)
+	if err != nil {
+		return newError(funcName, fileName, "error encoding key", err)
+	}
+	b = append(b, '\n')
+
+	err = writeFileAtomic(fileName, encryptFilePerm, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+	if err != nil {
+		return newError(funcName, fileName, "error writing file", err)
+	}
+
+	return nil
+}
+
+// Encrypt reads src to the end and writes it to dst encrypted with key.
+func Encrypt(dst io.Writer, src io.Reader, key EncryptionKey) error {
+	var funcName string = "Encrypt"
+
+	if err := encrypt(dst, src, key); err != nil {
+		return newTargetError(funcName, "", key.ID, "error encrypting", err)
+	}
+
+	return nil
+}
+
+// Decrypt reads an encrypted stream from src and writes the plaintext to dst,
+// using whichever of keys matches the key ID in the header. Each frame is
+// authenticated before it is written, but truncation is only detected at the
+// end of the stream, so dst may have received a prefix of the plaintext when
+// an error is returned. DecryptFile never leaves partial output.
+func Decrypt(dst io.Writer, src io.Reader, keys ...EncryptionKey) error {
+	var funcName string = "Decrypt"
+
+	if err := decrypt(dst, src, keys); err != nil {
+		return newError(funcName, "", "error decrypting", err)
+	}
+
+	return nil
+}
+
+// EncryptFile atomically writes src encrypted with key to dst.
+func EncryptFile(src, dst string, key EncryptionKey) error {
+	var funcName string = "EncryptFile"
+
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return newError(funcName, src, "error opening file", err)
+	}
+	defer in.Close()
+
+	err = writeFileAtomic(dst, encryptFilePerm, func(w io.Writer) error {
+		return encrypt(w, in, key)
+	})
+	if err != nil {
+		return newTargetError(funcName, src, dst, "error encrypting file", err)
+	}
+
+	return nil
+}
+
+// DecryptFile decrypts src to dst, which is only replaced once the whole
+// file has been authenticated.
+func DecryptFile(src, dst string, keys ...EncryptionKey) error {
+	var funcName string = "DecryptFile"
+
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return newError(funcName, src, "error opening file", err)
+	}
+	defer in.Close()
+
+	err = writeFileAtomic(dst, encryptFilePerm, func(w io.Writer) error {
+		return decrypt(w, in, keys)
+	})
+	if err != nil {
+		return newTargetError(funcName, src, dst, "error decrypting file", err)
+	}
+
+	return nil
+}
+
+func encrypt(dst io.Writer, src io.Reader, key EncryptionKey) error {
+	if len(key.ID) > 255 {
+		return fmt.Errorf("key id longer than 255 bytes: %w", ErrInvalid)
+	}
+
+	if len(key.Key) != KeySize {
+		return ErrInvalid
+	}
+
+	params := encryptParams{
+		chunkSize: encryptChunkSize,
+		salt:      make([]byte, encryptSaltSize),
+		prefix:    make([]byte, encryptPrefixSize),
+		keyID:     key.ID,
+	}
+	if _, err := rand.Read(params.salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(params.prefix); err != nil {
+		return err
+	}
+
+	aead, err := newGCM(streamKey(key.Key, params.salt))
+	if err != nil {
+		return err
+	}
+
+	header := params.header()
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(src, encryptChunkSize)
+	chunk := make([]byte, encryptChunkSize)
+	frame := make([]byte, 0, encryptChunkSize+aead.Overhead())
+
+	for counter := uint32(0); ; counter++ {
+		n, final, err := readChunk(br, chunk)
+		if err != nil {
+			return err
+		}
+
+		frame = aead.Seal(frame[:0], frameNonce(params.prefix, counter, final), chunk[:n], header)
+		if _, err := dst.Write(frame); err != nil {
+			return err
+		}
+
+		if final {
+			return nil
+		}
+
+		if counter == ^uint32(0) {
+			return fmt.Errorf("stream longer than %d chunks: %w", uint64(^uint32(0))+1, ErrInvalid)
+		}
+	}
+}
+
+func decrypt(dst io.Writer, src io.Reader, keys []EncryptionKey) error {
+	br := bufio.NewReader(src)
+
+	params, header, err := readEncryptHeader(br)
+	if err != nil {
+		return err
+	}
+
+	var aead cipher.AEAD
+	for _, k := range keys {
+		if k.ID == params.keyID {
+			if len(k.Key) != KeySize {
+				return ErrInvalid
+			}
+			aead, err = newGCM(streamKey(k.Key, params.salt))
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if aead == nil {
+		return fmt.Errorf("no key for key id %q: %w", params.keyID, ErrInvalid)
+	}
+
+	frame := make([]byte, params.chunkSize+aead.Overhead())
+	var plain []byte
+
+	for counter := uint32(0); ; counter++ {
+		n, final, err := readChunk(br, frame)
+		if err != nil {
+			return err
+		}
+
+		plain, err = aead.Open(plain[:0], frameNonce(params.prefix, counter, final), frame[:n], header)
+		if err != nil {
+			return ErrAuthentication
+		}
+
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+
+		if final {
+			return nil
+		}
+	}
+}
+
+// readChunk fills buf from br and reports whether the stream ends after it.
+func readChunk(br *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(br, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, true, nil
+	}
+	if err != nil {
+		return n, false, err
+	}
+
+	if _, err := br.Peek(1); err != nil {
+		if errors.Is(err, io.EOF) {
+			return n, true, nil
+		}
+		return n, false, err
+	}
+
+	return n, false, nil
+}
+
+type encryptParams struct {
+	chunkSize int
+	salt      []byte
+	prefix    []byte
+	keyID     string
+}
+
+func (p encryptParams) header() []byte {
+	var b bytes.Buffer
+
+	b.WriteString(encryptMagic)
+	b.WriteByte(encryptVersion)
+	_ = binary.Write(&b, binary.BigEndian, uint32(p.chunkSize))
+	b.Write(p.salt)
+	b.Write(p.prefix)
+	b.WriteByte(byte(len(p.keyID)))
+	b.WriteString(p.keyID)
+
+	return b.Bytes()
+}
+
+// readEncryptHeader returns the parameters of a stream and its header bytes.
+func readEncryptHeader(r io.Reader) (encryptParams, []byte, error) {
+	var p encryptParams
+
+	fixed := make([]byte, len(encryptMagic)+1+4+encryptSaltSize+encryptPrefixSize+1)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return p, nil, ErrInvalid
+	}
+
+	if string(fixed[:len(encryptMagic)]) != encryptMagic {
+		return p, nil, ErrInvalid
+	}
+	if fixed[len(encryptMagic)] != encryptVersion {
+		return p, nil, fmt.Errorf("unsupported version %d: %w", fixed[len(encryptMagic)], ErrInvalid)
+	}
+
+	off := len(encryptMagic) + 1
+	chunkSize := binary.BigEndian.Uint32(fixed[off:])
+	if chunkSize == 0 || chunkSize > encryptMaxChunkSize {
+		return p, nil, ErrInvalid
+	}
+	p.chunkSize = int(chunkSize)
+	off += 4
+
+	p.salt = fixed[off : off+encryptSaltSize]
+	off += encryptSaltSize
+
+	p.prefix = fixed[off : off+encryptPrefixSize]
+	off += encryptPrefixSize
+
+	keyID := make([]byte, fixed[off])
+	if _, err := io.ReadFull(r, keyID); err != nil {
+		return p, nil, ErrInvalid
+	}
+	p.keyID = string(keyID)
+
+	return p, append(fixed, keyID...), nil
+}
+
+// streamKey derives the key a stream is sealed with from key and the salt in
+// its header.
+func streamKey(key, salt []byte) []byte {
+	return hkdfSHA256(key, salt, []byte(encryptKeyInfo))
+}
+
+// hkdfSHA256 is HKDF (RFC 5869) with SHA-256, producing a single 32 byte
+// block of output.
+func hkdfSHA256(secret, salt, info []byte) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write(info)
+	expand.Write([]byte{1})
+
+	return expand.Sum(nil)
+}
+
+func frameNonce(prefix []byte, counter uint32, final bool) []byte {
+	nonce := make([]byte, encryptPrefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encryptPrefixSize:], counter)
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+
+	return nonce
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalid
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
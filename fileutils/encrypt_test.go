@@ -0,0 +1,264 @@
+package fileutils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncrypt(t *testing.T) {
+	key, err := NewEncryptionKey("primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewEncryptionKey("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	large := make([]byte, 3*encryptChunkSize+17)
+	if _, err := rand.Read(large); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]byte{
+		"empty":       {},
+		"short":       []byte("test content"),
+		"exact chunk": bytes.Repeat([]byte("x"), encryptChunkSize),
+		"large":       large,
+	}
+
+	for name, plain := range tests {
+		var enc bytes.Buffer
+		if err := Encrypt(&enc, bytes.NewReader(plain), key); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if len(plain) > 0 && bytes.Contains(enc.Bytes(), plain) {
+			t.Errorf("%s: expected plaintext not to appear in output", name)
+		}
+
+		var dec bytes.Buffer
+		if err := Decrypt(&dec, bytes.NewReader(enc.Bytes()), other, key); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(plain, dec.Bytes()) {
+			t.Errorf("%s: expected round trip to match", name)
+		}
+
+		ciphertext := enc.Bytes()
+		headerLen := len(encryptParams{salt: make([]byte, encryptSaltSize), prefix: make([]byte, encryptPrefixSize), keyID: key.ID}.header())
+
+		tampered := map[string][]byte{
+			"flipped":   flipByte(ciphertext, len(ciphertext)-1),
+			"header":    flipByte(ciphertext, headerLen-1),
+			"truncated": ciphertext[:len(ciphertext)-1],
+		}
+		if len(plain) > encryptChunkSize {
+			// drop the last frame, leaving whole frames only
+			tampered["dropped frame"] = ciphertext[:headerLen+encryptChunkSize+16]
+		}
+
+		for tname, b := range tampered {
+			err := Decrypt(&bytes.Buffer{}, bytes.NewReader(b), key)
+			if err == nil {
+				t.Errorf("%s %s: expected error, got nil", name, tname)
+			}
+		}
+
+		err = Decrypt(&bytes.Buffer{}, bytes.NewReader(tampered["flipped"]), key)
+		if !errors.Is(err, ErrAuthentication) {
+			t.Errorf("%s: expected ErrAuthentication, got %v", name, err)
+		}
+	}
+
+	var enc bytes.Buffer
+	if err := Encrypt(&enc, bytes.NewReader([]byte("test")), key); err != nil {
+		t.Fatal(err)
+	}
+
+	err = Decrypt(&bytes.Buffer{}, bytes.NewReader(enc.Bytes()), other)
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for a missing key, got %v", err)
+	}
+
+	wrong := other
+	wrong.ID = key.ID
+	err = Decrypt(&bytes.Buffer{}, bytes.NewReader(enc.Bytes()), wrong)
+	if !errors.Is(err, ErrAuthentication) {
+		t.Errorf("expected ErrAuthentication for the wrong key, got %v", err)
+	}
+
+	err = Decrypt(&bytes.Buffer{}, bytes.NewReader([]byte("not encrypted")), key)
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for a plain stream, got %v", err)
+	}
+	long := key
+	long.ID = strings.Repeat("k", 256)
+	err = Encrypt(&bytes.Buffer{}, bytes.NewReader([]byte("test")), long)
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for a long key id, got %v", err)
+	}
+}
+
+func TestEncryptStreamKeys(t *testing.T) {
+	key, err := NewEncryptionKey("primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every stream gets its own salt and so its own key
+	var a, b bytes.Buffer
+	for _, buf := range []*bytes.Buffer{&a, &b} {
+		if err := Encrypt(buf, bytes.NewReader([]byte("same content")), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pa, _, err := readEncryptHeader(bytes.NewReader(a.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, _, err := readEncryptHeader(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(pa.salt, pb.salt) {
+		t.Errorf("expected a fresh salt per stream")
+	}
+	if bytes.Equal(streamKey(key.Key, pa.salt), streamKey(key.Key, pb.salt)) {
+		t.Errorf("expected a different key per stream")
+	}
+
+	// RFC 5869 test case 1, the first block of output
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	expected := "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf"
+
+	actual := hex.EncodeToString(hkdfSHA256(bytes.Repeat([]byte{0x0b}, 22), salt, info))
+	if actual != expected {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestEncryptFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.json")
+	plainFile := filepath.Join(dir, "plain.txt")
+	encFile := filepath.Join(dir, "plain.txt.enc")
+	decFile := filepath.Join(dir, "decrypted.txt")
+
+	key, err := NewEncryptionKey("primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = WriteKeyFile(keyFile, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("expected key file mode 0600, got %v", fi.Mode().Perm())
+	}
+
+	loaded, err := ReadKeyFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ID != key.ID || !bytes.Equal(loaded.Key, key.Key) {
+		t.Errorf("expected loaded key to match")
+	}
+
+	err = os.WriteFile(plainFile, []byte("test content"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = EncryptFile(plainFile, encFile, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DecryptFile(encFile, decFile, loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(decFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "test content" {
+		t.Errorf("expected test content, got %q", contents)
+	}
+
+	// a missing key is reported once, by DecryptFile
+	other, err := NewEncryptionKey("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = DecryptFile(encFile, decFile, other)
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid, got %v", err)
+	}
+	var ferr *Error
+	if !errors.As(err, &ferr) || ferr.Op != "DecryptFile" || errors.As(ferr.Err, new(*Error)) {
+		t.Errorf("expected one DecryptFile error, got %v", err)
+	}
+
+	// a tampered file leaves the existing output alone
+	b, err := os.ReadFile(encFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(encFile, b[:len(b)-4], 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DecryptFile(encFile, decFile, key)
+	if !errors.Is(err, ErrAuthentication) {
+		t.Errorf("expected ErrAuthentication, got %v", err)
+	}
+
+	contents, err = os.ReadFile(decFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "test content" {
+		t.Errorf("expected previous output to be kept, got %q", contents)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("expected temp files to be cleaned up, got %d entries", len(entries))
+	}
+
+	err = os.WriteFile(keyFile, []byte(`{"id":"short","key":"AAAA"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadKeyFile(keyFile); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for a short key, got %v", err)
+	}
+}
+
+func flipByte(b []byte, i int) []byte {
+	c := append([]byte(nil), b...)
+	c[i] ^= 0x01
+	return c
+}
@@ -0,0 +1,88 @@
+package fileutils
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// Error is returned by every fileutils function that fails. The cause is kept
+// so errors.Is and errors.As see through it, for example
+// errors.Is(err, fs.ErrNotExist) or errors.Is(err, ErrNotFolder).
+type Error struct {
+	Package string
+	Op      string
+	Path    string
+	// second operand when there is one, a destination path or attribute name
+	Target string
+	// the step that failed, empty when Err says it all
+	Msg string
+	Err error
+}
+
+func (e *Error) Error() string {
+	s := fmt.Sprintf("%v.%v: ", e.Package, e.Op)
+
+	if e.Msg != "" {
+		s += e.Msg
+	} else if e.Err != nil {
+		s += e.Err.Error()
+	}
+
+	if e.Path != "" {
+		s += fmt.Sprintf(" [%v]", e.Path)
+	}
+	if e.Target != "" {
+		s += fmt.Sprintf(" [%v]", e.Target)
+	}
+
+	if e.Msg != "" && e.Err != nil {
+		s += fmt.Sprintf(", [%v]", e.Err.Error())
+	}
+
+	return s
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// sentinelError is a condition reported by fileutils itself, which may also
+// match one of the io/fs errors.
+type sentinelError struct {
+	msg string
+	is  error
+}
+
+func (e *sentinelError) Error() string {
+	return e.msg
+}
+
+func (e *sentinelError) Is(target error) bool {
+	return e.is != nil && target == e.is
+}
+
+var (
+	ErrNotExist       error = &sentinelError{msg: "target does not exist", is: fs.ErrNotExist}
+	ErrExist          error = &sentinelError{msg: "file already exists", is: fs.ErrExist}
+	ErrInvalid        error = &sentinelError{msg: "invalid argument", is: fs.ErrInvalid}
+	ErrClosed         error = &sentinelError{msg: "file is closed", is: fs.ErrClosed}
+	ErrNotFolder      error = &sentinelError{msg: "target is not a folder"}
+	ErrNotRegular     error = &sentinelError{msg: "target is not a regular file"}
+	ErrIsSymlink      error = &sentinelError{msg: "target is a symlink"}
+	ErrNotSymlink     error = &sentinelError{msg: "target exists and is not a symlink"}
+	ErrSymlinkLoop    error = &sentinelError{msg: "too many levels of symbolic links"}
+	ErrNotAbsolute    error = &sentinelError{msg: "paths must be absolute"}
+	ErrUnsupported    error = &sentinelError{msg: "not supported on this platform"}
+	ErrBadStatus      error = &sentinelError{msg: "bad return status"}
+	ErrChecksum       error = &sentinelError{msg: "checksum mismatch"}
+	ErrAuthentication error = &sentinelError{msg: "message authentication failed"}
+	ErrTruncated      error = &sentinelError{msg: "file shrank while mapped"}
+)
+
+func newError(op, path, msg string, err error) *Error {
+	return &Error{Package: packageName, Op: op, Path: path, Msg: msg, Err: err}
+}
+
+func newTargetError(op, path, target, msg string, err error) *Error {
+	return &Error{Package: packageName, Op: op, Path: path, Target: target, Msg: msg, Err: err}
+}
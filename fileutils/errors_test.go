@@ -0,0 +1,138 @@
+package fileutils
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	readOnly := filepath.Join(dir, "readonly")
+	err := MkDir(readOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chmod(readOnly, 0555)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(readOnly, 0755) //nolint:errcheck
+
+	tests := map[string]struct {
+		fn       func() error
+		expected []error
+	}{
+		"find missing": {
+			fn: func() error {
+				_, err := Find(filepath.Join(dir, "nofolder"), ".txt")
+				return err
+			},
+			expected: []error{ErrNotExist, fs.ErrNotExist},
+		},
+		"find file": {
+			fn: func() error {
+				_, err := Find("testdata/testfile.txt", ".txt")
+				return err
+			},
+			expected: []error{ErrNotFolder},
+		},
+		"find symlink": {
+			fn: func() error {
+				_, err := Find("testdata/testsymlinkfolder", ".txt")
+				return err
+			},
+			expected: []error{ErrIsSymlink},
+		},
+		"mkfile existing": {
+			fn: func() error {
+				return MkFile("testdata/testfile.txt")
+			},
+			expected: []error{ErrExist, fs.ErrExist},
+		},
+		"hash missing": {
+			fn: func() error {
+				_, err := FileHash(filepath.Join(dir, "nofile.txt"))
+				return err
+			},
+			expected: []error{fs.ErrNotExist},
+		},
+		"write file wrapped twice": {
+			fn: func() error {
+				return WriteFile(filepath.Join(dir, "nofolder", "file.txt"), "content")
+			},
+			expected: []error{fs.ErrNotExist},
+		},
+		"is symlink missing": {
+			fn: func() error {
+				_, err := IsSymlink(filepath.Join(dir, "nofile.txt"))
+				return err
+			},
+			expected: []error{fs.ErrNotExist},
+		},
+	}
+
+	if os.Geteuid() != 0 {
+		tests["write read only folder"] = struct {
+			fn       func() error
+			expected []error
+		}{
+			fn: func() error {
+				return WriteFile(filepath.Join(readOnly, "file.txt"), "content")
+			},
+			expected: []error{fs.ErrPermission},
+		}
+	}
+
+	for name, tt := range tests {
+		err := tt.fn()
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+			continue
+		}
+
+		var fe *Error
+		if !errors.As(err, &fe) {
+			t.Errorf("%s: expected *Error, got %T", name, err)
+		}
+
+		for _, target := range tt.expected {
+			if !errors.Is(err, target) {
+				t.Errorf("%s: expected errors.Is(%v), got %v", name, target, err)
+			}
+		}
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		expected string
+	}{
+		"sentinel": {
+			err:      newError("Find", "testdata/nofolder", "", ErrNotExist),
+			expected: "fileutils.Find: target does not exist [testdata/nofolder]",
+		},
+		"cause": {
+			err:      newError("FileHash", "testdata/nofile.txt", "error opening file", fs.ErrNotExist),
+			expected: "fileutils.FileHash: error opening file [testdata/nofile.txt], [file does not exist]",
+		},
+		"target": {
+			err:      newTargetError("CopyFile", "a.txt", "b.txt", "error copying file", fs.ErrPermission),
+			expected: "fileutils.CopyFile: error copying file [a.txt] [b.txt], [permission denied]",
+		},
+		"no path": {
+			err:      newError("DetectType", "", "error reading content", fs.ErrClosed),
+			expected: "fileutils.DetectType: error reading content, [file already closed]",
+		},
+	}
+
+	for name, tt := range tests {
+		if actual := tt.err.Error(); tt.expected != actual {
+			t.Errorf("%s: expected %q, got %q", name, tt.expected, actual)
+		}
+	}
+}
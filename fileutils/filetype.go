@@ -0,0 +1,262 @@
+package fileutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
+
+// number of bytes read from the start of a file to detect its type.
+const sniffLen = 512
+
+const (
+	mimeBinary   = "application/octet-stream"
+	mimeText     = "text/plain"
+	mimeTextUTF8 = "text/plain; charset=utf-8"
+)
+
+type FileType struct {
+	MIME   string
+	Binary bool
+}
+
+func (t FileType) IsBinary() bool {
+	return t.Binary
+}
+
+func (t FileType) IsText() bool {
+	return !t.Binary
+}
+
+// Signature matches Magic at Offset bytes into a file.
+type Signature struct {
+	Offset int
+	Magic  []byte
+	MIME   string
+	Binary bool
+
+	// further test for built in magic numbers too short to trust alone
+	check func(head []byte) bool
+}
+
+var signaturesMu sync.RWMutex
+
+// checked in order, so longer magic numbers sharing a prefix come first.
+var signatures = []Signature{
+	// text encodings with a byte order mark
+	{Magic: []byte{0xFF, 0xFE, 0x00, 0x00}, MIME: "text/plain; charset=utf-32le"},
+	{Magic: []byte{0x00, 0x00, 0xFE, 0xFF}, MIME: "text/plain; charset=utf-32be"},
+	{Magic: []byte{0xEF, 0xBB, 0xBF}, MIME: mimeTextUTF8},
+	{Magic: []byte{0xFF, 0xFE}, MIME: "text/plain; charset=utf-16le"},
+	{Magic: []byte{0xFE, 0xFF}, MIME: "text/plain; charset=utf-16be"},
+
+	// archives and compression
+	{Magic: []byte("PK\x03\x04"), MIME: "application/zip", Binary: true},
+	{Magic: []byte("PK\x05\x06"), MIME: "application/zip", Binary: true},
+	{Magic: []byte{0x1F, 0x8B}, MIME: "application/gzip", Binary: true},
+	{Magic: []byte("BZh"), MIME: "application/x-bzip2", Binary: true, check: isBzip2},
+	{Magic: []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}, MIME: "application/x-xz", Binary: true},
+	{Magic: []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}, MIME: "application/x-7z-compressed", Binary: true},
+	{Magic: []byte{0x28, 0xB5, 0x2F, 0xFD}, MIME: "application/zstd", Binary: true},
+	{Magic: []byte("Rar!\x1A\x07"), MIME: "application/vnd.rar", Binary: true},
+	{Offset: 257, Magic: []byte("ustar"), MIME: "application/x-tar", Binary: true},
+
+	// images
+	{Magic: []byte("\x89PNG\r\n\x1A\n"), MIME: "image/png", Binary: true},
+	{Magic: []byte{0xFF, 0xD8, 0xFF}, MIME: "image/jpeg", Binary: true},
+	{Magic: []byte("GIF87a"), MIME: "image/gif", Binary: true},
+	{Magic: []byte("GIF89a"), MIME: "image/gif", Binary: true},
+	{Offset: 8, Magic: []byte("WEBP"), MIME: "image/webp", Binary: true},
+	{Magic: []byte("II*\x00"), MIME: "image/tiff", Binary: true},
+	{Magic: []byte("MM\x00*"), MIME: "image/tiff", Binary: true},
+	{Magic: []byte{0x00, 0x00, 0x01, 0x00}, MIME: "image/x-icon", Binary: true},
+
+	// documents
+	{Magic: []byte("%PDF-"), MIME: "application/pdf", Binary: true},
+	{Magic: []byte("SQLite format 3\x00"), MIME: "application/vnd.sqlite3", Binary: true},
+
+	// executables
+	{Magic: []byte("\x7FELF"), MIME: "application/x-elf", Binary: true},
+	{Magic: []byte{0xCF, 0xFA, 0xED, 0xFE}, MIME: "application/x-mach-binary", Binary: true},
+	{Magic: []byte{0xCE, 0xFA, 0xED, 0xFE}, MIME: "application/x-mach-binary", Binary: true},
+	{Magic: []byte("MZ"), MIME: "application/vnd.microsoft.portable-executable", Binary: true, check: isPE},
+	{Magic: []byte("\x00asm"), MIME: "application/wasm", Binary: true},
+}
+
+// RegisterSignature adds s to the signature table. Registered signatures are
+// checked before the built in ones, most recent first.
+func RegisterSignature(s Signature) {
+	signaturesMu.Lock()
+	defer signaturesMu.Unlock()
+
+	signatures = append([]Signature{s}, signatures...)
+}
+
+// DetectType reads up to the first 512 bytes of r and works out its type.
+func DetectType(r io.Reader) (FileType, error) {
+	var funcName string = "DetectType"
+
+	head, err := readHead(r)
+	if err != nil {
+		return FileType{}, newError(funcName, "", "error reading content", err)
+	}
+
+	return detectType(head), nil
+}
+
+// PeekType is DetectType for readers that cannot be reopened, such as an http
+// response body. The returned reader replays the bytes that were inspected.
+func PeekType(r io.Reader) (FileType, io.Reader, error) {
+	var funcName string = "PeekType"
+
+	head, err := readHead(r)
+	if err != nil {
+		return FileType{}, r, newError(funcName, "", "error reading content", err)
+	}
+
+	return detectType(head), io.MultiReader(bytes.NewReader(head), r), nil
+}
+
+func DetectFileType(fileName string) (FileType, error) {
+	var funcName string = "DetectFileType"
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return FileType{}, newError(funcName, fileName, "error opening file", err)
+	}
+	defer f.Close()
+
+	head, err := readHead(f)
+	if err != nil {
+		return FileType{}, newError(funcName, fileName, "error reading file", err)
+	}
+
+	return detectType(head), nil
+}
+
+func IsBinary(fileName string) (bool, error) {
+	t, err := DetectFileType(fileName)
+	if err != nil {
+		return false, err
+	}
+
+	return t.Binary, nil
+}
+
+func IsText(fileName string) (bool, error) {
+	t, err := DetectFileType(fileName)
+	if err != nil {
+		return false, err
+	}
+
+	return !t.Binary, nil
+}
+
+// IsTextFile is a FindFunc match function selecting regular files that are
+// detected as text.
+func IsTextFile(fileName string, d fs.DirEntry) bool {
+	if !d.Type().IsRegular() {
+		return false
+	}
+
+	text, err := IsText(fileName)
+
+	return err == nil && text
+}
+
+func readHead(r io.Reader) ([]byte, error) {
+	head := make([]byte, sniffLen)
+
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+
+	return head[:n], nil
+}
+
+// sniffHead returns the part of b that DetectType would look at.
+func sniffHead(b []byte) []byte {
+	if len(b) > sniffLen {
+		return b[:sniffLen]
+	}
+
+	return b
+}
+
+func detectType(head []byte) FileType {
+	signaturesMu.RLock()
+	defer signaturesMu.RUnlock()
+
+	for _, s := range signatures {
+		if len(head) < s.Offset+len(s.Magic) || !bytes.Equal(head[s.Offset:s.Offset+len(s.Magic)], s.Magic) {
+			continue
+		}
+		if s.check == nil || s.check(head) {
+			return FileType{MIME: s.MIME, Binary: s.Binary}
+		}
+	}
+
+	if looksBinary(head) {
+		return FileType{MIME: mimeBinary, Binary: true}
+	}
+
+	if validUTF8Prefix(head) {
+		return FileType{MIME: mimeTextUTF8}
+	}
+
+	return FileType{MIME: mimeText}
+}
+
+// isBzip2 wants the block size digit after "BZh".
+func isBzip2(head []byte) bool {
+	return len(head) > 3 && head[3] >= '1' && head[3] <= '9'
+}
+
+// isPE follows e_lfanew, the offset at 0x3C of the DOS header, to the PE
+// signature. Headers placed past what is sniffed are not recognised.
+func isPE(head []byte) bool {
+	if len(head) < 0x40 {
+		return false
+	}
+
+	off := int64(binary.LittleEndian.Uint32(head[0x3C:]))
+
+	return off+4 <= int64(len(head)) && string(head[off:off+4]) == "PE\x00\x00"
+}
+
+// looksBinary treats any NUL byte, or more than 10% control characters other
+// than whitespace, as binary content.
+func looksBinary(head []byte) bool {
+	if bytes.IndexByte(head, 0x00) != -1 {
+		return true
+	}
+
+	var control int
+	for _, b := range head {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' && b != '\b' && b != 0x1B {
+			control++
+		}
+	}
+
+	return control*10 > len(head)
+}
+
+// validUTF8Prefix allows the sample to end part way through a rune.
+func validUTF8Prefix(head []byte) bool {
+	for i := len(head) - 1; i >= 0 && i >= len(head)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(head[i]) {
+			if !utf8.FullRune(head[i:]) {
+				return utf8.Valid(head[:i])
+			}
+			break
+		}
+	}
+
+	return utf8.Valid(head)
+}
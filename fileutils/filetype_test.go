@@ -0,0 +1,199 @@
+package fileutils
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectType(t *testing.T) {
+	tar := make([]byte, 300)
+	copy(tar[257:], "ustar")
+
+	pe := make([]byte, 0x100)
+	copy(pe, "MZ")
+	pe[0x3C] = 0x80
+	copy(pe[0x80:], "PE\x00\x00")
+
+	tests := map[string]struct {
+		content  []byte
+		expected FileType
+	}{
+		"empty": {
+			content:  []byte{},
+			expected: FileType{MIME: "text/plain; charset=utf-8"},
+		},
+		"ascii": {
+			content:  []byte("id,name\n1,foo\n"),
+			expected: FileType{MIME: "text/plain; charset=utf-8"},
+		},
+		"latin1": {
+			content:  []byte("caf\xe9 cr\xe8me\n"),
+			expected: FileType{MIME: "text/plain"},
+		},
+		"utf8 bom": {
+			content:  []byte("\xef\xbb\xbfhello"),
+			expected: FileType{MIME: "text/plain; charset=utf-8"},
+		},
+		"utf16le bom": {
+			content:  []byte("\xff\xfeh\x00i\x00"),
+			expected: FileType{MIME: "text/plain; charset=utf-16le"},
+		},
+		"gzip": {
+			content:  []byte{0x1f, 0x8b, 0x08, 0x00},
+			expected: FileType{MIME: "application/gzip", Binary: true},
+		},
+		"zip": {
+			content:  []byte("PK\x03\x04\x14\x00"),
+			expected: FileType{MIME: "application/zip", Binary: true},
+		},
+		"tar": {
+			content:  tar,
+			expected: FileType{MIME: "application/x-tar", Binary: true},
+		},
+		"png": {
+			content:  []byte("\x89PNG\r\n\x1a\n\x00\x00"),
+			expected: FileType{MIME: "image/png", Binary: true},
+		},
+		"pdf": {
+			content:  []byte("%PDF-1.7\n"),
+			expected: FileType{MIME: "application/pdf", Binary: true},
+		},
+		"elf": {
+			content:  []byte("\x7fELF\x02\x01\x01"),
+			expected: FileType{MIME: "application/x-elf", Binary: true},
+		},
+		"bzip2": {
+			content:  []byte("BZh91AY&SY"),
+			expected: FileType{MIME: "application/x-bzip2", Binary: true},
+		},
+		"text starting BZh": {
+			content:  []byte("BZh, the bzip2 magic, explained\n"),
+			expected: FileType{MIME: "text/plain; charset=utf-8"},
+		},
+		"pe": {
+			content:  pe,
+			expected: FileType{MIME: "application/vnd.microsoft.portable-executable", Binary: true},
+		},
+		"text starting MZ": {
+			content:  []byte("MZ-80 emulator notes\n" + strings.Repeat("line of text\n", 10)),
+			expected: FileType{MIME: "text/plain; charset=utf-8"},
+		},
+		"unknown binary": {
+			content:  []byte{0x01, 0x02, 0x00, 0x03},
+			expected: FileType{MIME: "application/octet-stream", Binary: true},
+		},
+	}
+
+	for name, tt := range tests {
+		actual, err := DetectType(bytes.NewReader(tt.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%s: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestDetectTypeSplitRune(t *testing.T) {
+	// a multi byte rune straddling the sniff limit is still utf-8
+	content := strings.Repeat("a", sniffLen-1) + "é"
+
+	actual, err := DetectType(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.MIME != "text/plain; charset=utf-8" {
+		t.Errorf("expected utf-8 text, got %v", actual.MIME)
+	}
+}
+
+func TestPeekType(t *testing.T) {
+	content := "%PDF-1.7\n" + strings.Repeat("x", sniffLen*2)
+
+	ft, r, err := PeekType(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ft.MIME != "application/pdf" {
+		t.Errorf("expected application/pdf, got %v", ft.MIME)
+	}
+
+	actual, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(actual) != content {
+		t.Errorf("expected reader to replay the full content")
+	}
+}
+
+func TestRegisterSignature(t *testing.T) {
+	signaturesMu.RLock()
+	saved := signatures
+	signaturesMu.RUnlock()
+	defer func() {
+		signaturesMu.Lock()
+		signatures = saved
+		signaturesMu.Unlock()
+	}()
+
+	RegisterSignature(Signature{Magic: []byte("PAR1"), MIME: "application/vnd.apache.parquet", Binary: true})
+
+	actual, err := DetectType(strings.NewReader("PAR1...."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.MIME != "application/vnd.apache.parquet" || !actual.IsBinary() {
+		t.Errorf("expected registered signature to match, got %v", actual)
+	}
+}
+
+func TestFindFuncText(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string][]byte{
+		"notes.txt": []byte("hello\n"),
+		"image.png": []byte("\x89PNG\r\n\x1a\n\x00\x00"),
+		"data.csv":  []byte("a,b\n"),
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), content, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actual, err := FindFunc(dir, IsTextFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "data.csv"),
+		filepath.Join(dir, "notes.txt"),
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	binary, err := IsBinary(filepath.Join(dir, "image.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !binary {
+		t.Errorf("expected image.png to be binary")
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"io/fs"
 	"io/ioutil"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
 	"syscall"
@@ -21,38 +20,47 @@ const (
 )
 
 func Find(folderPath, ext string) ([]string, error) {
-	var funcName string = "Find"
+	return find("Find", folderPath, func(s string, d fs.DirEntry) bool {
+		return filepath.Ext(d.Name()) == ext
+	})
+}
+
+// FindFunc is Find with the extension check replaced by match.
+func FindFunc(folderPath string, match func(path string, d fs.DirEntry) bool) ([]string, error) {
+	return find("FindFunc", folderPath, match)
+}
 
+func find(funcName, folderPath string, match func(path string, d fs.DirEntry) bool) ([]string, error) {
 	var files []string
 
 	if !FolderExists(folderPath) {
-		return []string{}, fmt.Errorf("%v.%v: target does not exist [%v]", packageName, funcName, folderPath)
+		return []string{}, newError(funcName, folderPath, "", ErrNotExist)
 	}
 
 	if !IsFolder(folderPath) {
-		return []string{}, fmt.Errorf("%v.%v: target is not a folder [%v]", packageName, funcName, folderPath)
+		return []string{}, newError(funcName, folderPath, "", ErrNotFolder)
 	}
 
 	sym, err := IsSymlink(folderPath)
 	if err != nil {
-		return []string{}, fmt.Errorf("%v.%v: error checking symlink [%v], [%v]", packageName, funcName, folderPath, err.Error())
+		return []string{}, newError(funcName, folderPath, "error checking symlink", err)
 	}
 	if sym {
-		return []string{}, fmt.Errorf("%v.%v: target is a symlink [%v]", packageName, funcName, folderPath)
+		return []string{}, newError(funcName, folderPath, "", ErrIsSymlink)
 	}
 
 	err = filepath.WalkDir(folderPath, func(s string, d fs.DirEntry, e error) error {
 		if e != nil {
 			return e
 		}
-		if filepath.Ext(d.Name()) == ext {
+		if match(s, d) {
 			files = append(files, s)
 		}
 		return nil
 	})
 
 	if err != nil {
-		return []string{}, fmt.Errorf("%v.%v: error walking target [%v], [%v]", packageName, funcName, folderPath, err.Error())
+		return []string{}, newError(funcName, folderPath, "error walking target", err)
 	}
 
 	return files, nil
@@ -62,11 +70,11 @@ func Folders(folderPath string) ([]string, error) {
 	var funcName string = "Folders"
 
 	if !FolderExists(folderPath) {
-		return []string{}, fmt.Errorf("%v.%v: target does not exist [%v]", packageName, funcName, folderPath)
+		return []string{}, newError(funcName, folderPath, "", ErrNotExist)
 	}
 
 	if !IsFolder(folderPath) {
-		return []string{}, fmt.Errorf("%v.%v: target is not a folder [%v]", packageName, funcName, folderPath)
+		return []string{}, newError(funcName, folderPath, "", ErrNotFolder)
 	}
 
 	var folders []string
@@ -83,7 +91,7 @@ func Folders(folderPath string) ([]string, error) {
 	})
 
 	if err != nil {
-		return []string{}, fmt.Errorf("%v.%v: error walking target [%v], [%v]", packageName, funcName, folderPath, err.Error())
+		return []string{}, newError(funcName, folderPath, "error walking target", err)
 	}
 
 	return folders, nil
@@ -93,30 +101,35 @@ func EmptyFolder(folderPath string) error {
 	var funcName string = "EmptyFolder"
 
 	if !FolderExists(folderPath) {
-		return fmt.Errorf("%v.%v: target does not exist [%v]", packageName, funcName, folderPath)
+		return newError(funcName, folderPath, "", ErrNotExist)
 	}
 
 	dir, err := ioutil.ReadDir(folderPath)
 	if err != nil {
-		return fmt.Errorf("%v.%v: error reading target [%v], [%v]", packageName, funcName, folderPath, err.Error())
+		return newError(funcName, folderPath, "error reading target", err)
 	}
 
+	// carry on past failures so as much as possible is removed
+	var firstErr error
 	for _, d := range dir {
-		os.RemoveAll(path.Join([]string{folderPath, d.Name()}...))
+		target := filepath.Join(folderPath, d.Name())
+		if err := os.RemoveAll(target); err != nil && firstErr == nil {
+			firstErr = newError(funcName, target, "error removing target", err)
+		}
 	}
 
-	return nil
+	return firstErr
 }
 
 func FolderIsWriteable(folderPath string) (bool, error) {
 	var funcName string = "FolderIsWritable"
 
 	if !FolderExists(folderPath) {
-		return false, fmt.Errorf("%v.%v: target does not exist [%v]", packageName, funcName, folderPath)
+		return false, newError(funcName, folderPath, "", ErrNotExist)
 	}
 
 	if !IsFolder(folderPath) {
-		return false, fmt.Errorf("%v.%v: target is not a folder [%v]", packageName, funcName, folderPath)
+		return false, newError(funcName, folderPath, "", ErrNotFolder)
 	}
 
 	return FileIsWriteable(folderPath), nil
@@ -154,27 +167,37 @@ func IsSymlink(fileName string) (bool, error) {
 
 	fi, err := os.Lstat(fileName)
 	if err != nil {
-		return false, fmt.Errorf("%v.%v: error checking file info [%v], [%v]", packageName, funcName, fileName, err.Error())
+		return false, newError(funcName, fileName, "error checking file info", err)
 	}
 
 	return fi.Mode()&os.ModeSymlink == os.ModeSymlink, nil
 }
 
 func MkDir(dir string) error {
-	var funcName string = "MkDir"
+	return mkDir("MkDir", dir, os.ModePerm)
+}
+
+// MkDirMode is MkDir with the permissions of any folders it creates set to
+// perm, before the umask is applied.
+func MkDirMode(dir string, perm os.FileMode) error {
+	return mkDir("MkDirMode", dir, perm)
+}
 
+func mkDir(funcName, dir string, perm os.FileMode) error {
 	var sym bool
 	var err error
 
 	if FileExists(dir) {
 		sym, err = IsSymlink(dir)
 		if err != nil {
-			return fmt.Errorf("%v.%v: error checking if symlink [%v], [%v]", packageName, funcName, dir, err.Error())
+			return newError(funcName, dir, "error checking if symlink", err)
 		}
 	}
 
 	if !sym {
-		return os.MkdirAll(dir, os.ModePerm)
+		if err := os.MkdirAll(dir, perm); err != nil {
+			return newError(funcName, dir, "error creating folder", err)
+		}
 	}
 
 	return nil
@@ -184,12 +207,12 @@ func MkFile(fileName string) error {
 	var funcName string = "MkFile"
 
 	if FileExists(fileName) {
-		return fmt.Errorf("%v.%v: file already exists [%v]", packageName, funcName, fileName)
+		return newError(funcName, fileName, "", ErrExist)
 	}
 
 	emptyFile, err := os.Create(fileName)
 	if err != nil {
-		return fmt.Errorf("%v.%v: error creating file [%v]", packageName, funcName, fileName)
+		return newError(funcName, fileName, "error creating file", err)
 	}
 	emptyFile.Close()
 
@@ -209,7 +232,7 @@ func GetFile(fileName string) (*os.File, error) {
 
 	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0655)
 	if err != nil {
-		return nil, fmt.Errorf("%v.%v: error opening file [%v], [%v]", packageName, funcName, fileName, err.Error())
+		return nil, newError(funcName, fileName, "error opening file", err)
 	}
 
 	return f, nil
@@ -220,13 +243,13 @@ func WriteFile(fileName string, fileContent string) error {
 
 	f, err := GetFile(fileName)
 	if err != nil {
-		return fmt.Errorf("%v.%v: error preparing to write file [%v], [%v]", packageName, funcName, fileName, err.Error())
+		return newError(funcName, fileName, "error preparing to write file", err)
 	}
 	defer f.Close()
 
 	_, err = f.Write([]byte(fileContent))
 	if err != nil {
-		return fmt.Errorf("%v.%v: error writing file [%v], [%v]", packageName, funcName, fileName, err.Error())
+		return newError(funcName, fileName, "error writing file", err)
 	}
 
 	return nil
@@ -236,7 +259,7 @@ func WriteLine(f *os.File, line string) error {
 	var funcName string = "WriteLine"
 
 	if _, err := f.Write([]byte(line)); err != nil {
-		return fmt.Errorf("%v.%v: error writing line [%v], [%v]", packageName, funcName, f.Name(), err.Error())
+		return newError(funcName, f.Name(), "error writing line", err)
 	}
 
 	return nil
@@ -247,14 +270,14 @@ func GetMD5Hash(filePath string) (string, error) {
 
 	file, err := os.Open(filePath)
 	if err != nil {
-		return "", fmt.Errorf("%v.%v: error opening file [%v], [%v]", packageName, funcName, filePath, err.Error())
+		return "", newError(funcName, filePath, "error opening file", err)
 	}
 	defer file.Close()
 
 	hash := md5.New() //nolint:gosec
 	_, err = io.Copy(hash, file)
 	if err != nil {
-		return "", fmt.Errorf("%v.%v: error hash file [%v], [%v]", packageName, funcName, filePath, err.Error())
+		return "", newError(funcName, filePath, "error hash file", err)
 	}
 
 	return fmt.Sprintf("%x", hash.Sum(nil)), nil
@@ -265,13 +288,13 @@ func FileSizeBytes(filePath string) (int64, error) {
 
 	file, err := os.Open(filePath)
 	if err != nil {
-		return 0, fmt.Errorf("%v.%v: error opening file [%v], [%v]", packageName, funcName, filePath, err.Error())
+		return 0, newError(funcName, filePath, "error opening file", err)
 	}
 	defer file.Close()
 
 	stat, err := file.Stat()
 	if err != nil {
-		return 0, fmt.Errorf("%v.%v: error getting file info [%v], [%v]", packageName, funcName, filePath, err.Error())
+		return 0, newError(funcName, filePath, "error getting file info", err)
 	}
 
 	return stat.Size(), nil
@@ -282,20 +305,8 @@ func FileSize(fileName, units string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	var bytes float64 = float64(fileBytes)
-
-	switch units {
-	case "kb":
-	case "mb":
-	case "gb":
-	case "tb":
-	case "pb":
-	case "xb":
-	case "zb":
-		return fmt.Sprintf("%.2f%v", ByteSizeConvert(fileBytes, units), units), nil
-	}
 
-	return fmt.Sprintf("%.0fb", bytes), nil
+	return byteSizeString(fileBytes, units), nil
 }
 
 func FileHash(fileName string) (string, error) {
@@ -303,13 +314,13 @@ func FileHash(fileName string) (string, error) {
 
 	f, err := os.Open(fileName)
 	if err != nil {
-		return "", fmt.Errorf("%v.%v: error opening file [%v], [%v]", packageName, funcName, fileName, err.Error())
+		return "", newError(funcName, fileName, "error opening file", err)
 	}
 	defer f.Close()
 
 	h := sha256.New()
 	if _, err := io.Copy(h, f); err != nil {
-		return "", fmt.Errorf("%v.%v: error hashing file [%v], [%v]", packageName, funcName, fileName, err.Error())
+		return "", newError(funcName, fileName, "error hashing file", err)
 	}
 
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
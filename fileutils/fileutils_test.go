@@ -3,12 +3,16 @@ package fileutils
 
 import (
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"testing"
+	"testing/fstest"
 )
 
 func TestMain(m *testing.M) {
@@ -18,6 +22,64 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	os.Exit(m.Run())
+}
+
+// testTree writes files into a new temp folder and returns it, for tests
+// that need more than the checked in testdata. Folders are created as needed,
+// symlinks take their target from Data, and Mode and ModTime are applied when
+// set, files default to 0644.
+func testTree(t *testing.T, files fstest.MapFS) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := files[name]
+		fileName := filepath.Join(dir, filepath.FromSlash(name))
+
+		err := MkDir(filepath.Dir(fileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch {
+		case f.Mode.IsDir():
+			err = MkDir(fileName)
+		case f.Mode&fs.ModeSymlink != 0:
+			err = os.Symlink(string(f.Data), fileName)
+		default:
+			err = os.WriteFile(fileName, f.Data, 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if f.Mode&fs.ModeSymlink != 0 {
+			continue
+		}
+
+		if f.Mode.Perm() != 0 {
+			if err := os.Chmod(fileName, f.Mode.Perm()); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if !f.ModTime.IsZero() {
+			if err := os.Chtimes(fileName, f.ModTime, f.ModTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	return dir
 }
 
 func TestIsSymlink(t *testing.T) {
@@ -227,7 +289,8 @@ func TestFolders(t *testing.T) {
 }
 
 func TestEmptyFolder(t *testing.T) {
-	targetFolder := "testdata/testfolder"
+	// not testdata/testfolder, emptying it would remove its .gitignore
+	targetFolder := t.TempDir()
 	extension := ".txt"
 
 	for i := 0; i <= 3; i++ {
@@ -352,6 +415,11 @@ func TestFileIsWriteable(t *testing.T) {
 	}
 
 	for name, tt := range tests {
+		// root can write to anything
+		if name == "perms 444" && os.Geteuid() == 0 {
+			continue
+		}
+
 		actual := FileIsWriteable(tt.path)
 
 		if tt.expected != actual {
@@ -574,6 +642,34 @@ func TestFileSizeBytes(t *testing.T) {
 	}
 }
 
+func TestFileSize(t *testing.T) {
+	targetFile := "testdata/testfolder/testfile.txt"
+
+	err := os.WriteFile(targetFile, make([]byte, 1536), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(targetFile)
+
+	tests := map[string]string{
+		"":   "1536b",
+		"b":  "1536b",
+		"kb": "1.50kb",
+		"mb": "0.00mb",
+	}
+
+	for units, expected := range tests {
+		actual, err := FileSize(targetFile, units)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual != expected {
+			t.Errorf("%v: expected size to equal %v [%v]", units, expected, actual)
+		}
+	}
+}
+
 func TestFileHash(t *testing.T) {
 	targetFile := "testdata/testfile.txt"
 	expected := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
@@ -0,0 +1,280 @@
+package fileutils
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loose defaults.
+var FollowPollInterval = 250 * time.Millisecond
+
+type FollowOptions struct {
+	// emit the last n lines already in the file before following, otherwise
+	// following starts at the end of the file
+	FromLines int
+	// persist the read offset here and resume from it on the next run,
+	// takes precedence over FromLines when it holds a usable offset
+	OffsetFile string
+	// how often to check for new data, defaults to FollowPollInterval
+	PollInterval time.Duration
+}
+
+type follower struct {
+	fileName string
+	opts     FollowOptions
+
+	f      *os.File
+	r      *bufio.Reader
+	inode  uint64
+	offset int64 // end of the last complete line read
+}
+
+// Follow streams lines appended to fileName, like tail -f. Truncation and
+// rotation by rename and recreate are detected, the old file is drained
+// before switching to the new one. Both channels are closed when ctx is done
+// or following fails, in which case the error is sent first.
+func Follow(ctx context.Context, fileName string, opts *FollowOptions) (<-chan string, <-chan error) {
+	lines := make(chan string)
+	errs := make(chan error, 1)
+
+	fl := &follower{fileName: fileName}
+	if opts != nil {
+		fl.opts = *opts
+	}
+	if fl.opts.PollInterval <= 0 {
+		fl.opts.PollInterval = FollowPollInterval
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(lines)
+
+		if err := fl.run(ctx, lines); err != nil {
+			errs <- err
+		}
+	}()
+
+	return lines, errs
+}
+
+func (fl *follower) run(ctx context.Context, lines chan<- string) error {
+	var funcName string = "Follow"
+
+	if err := fl.open(); err != nil {
+		return newError(funcName, fl.fileName, "error opening file", err)
+	}
+	defer func() {
+		fl.f.Close()
+	}()
+
+	if err := fl.seekStart(); err != nil {
+		return newError(funcName, fl.fileName, "error seeking file", err)
+	}
+
+	for {
+		n, err := fl.readLines(ctx, lines)
+		if err != nil {
+			return newError(funcName, fl.fileName, "error reading file", err)
+		}
+
+		if n > 0 && fl.opts.OffsetFile != "" {
+			if err := fl.saveOffset(); err != nil {
+				return newError(funcName, fl.opts.OffsetFile, "error saving offset", err)
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if n > 0 {
+			// keep reading while there is data
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(fl.opts.PollInterval):
+		}
+
+		rotated, err := fl.checkRotation()
+		if err != nil {
+			return newError(funcName, fl.fileName, "error checking file", err)
+		}
+
+		if rotated {
+			// drain whatever was written to the old file before the rename
+			if _, err := fl.readLines(ctx, lines); err != nil {
+				return newError(funcName, fl.fileName, "error reading file", err)
+			}
+
+			fl.f.Close()
+			if err := fl.open(); err != nil {
+				return newError(funcName, fl.fileName, "error reopening file", err)
+			}
+
+			if fl.opts.OffsetFile != "" {
+				if err := fl.saveOffset(); err != nil {
+					return newError(funcName, fl.opts.OffsetFile, "error saving offset", err)
+				}
+			}
+		}
+	}
+}
+
+func (fl *follower) open() error {
+	f, err := os.Open(fl.fileName)
+	if err != nil {
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	fl.f = f
+	fl.r = bufio.NewReader(f)
+	fl.inode = fileInode(fi)
+	fl.offset = 0
+
+	return nil
+}
+
+func (fl *follower) seekStart() error {
+	fi, err := fl.f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if fl.opts.OffsetFile != "" {
+		inode, offset, ok := loadFollowOffset(fl.opts.OffsetFile)
+		if ok {
+			if inode != fl.inode || offset > fi.Size() {
+				// the file we were following has been replaced
+				offset = 0
+			}
+			return fl.seek(offset)
+		}
+	}
+
+	if fl.opts.FromLines > 0 {
+		offset, err := lastLinesOffset(fl.f, fi.Size(), fl.opts.FromLines)
+		if err != nil {
+			return err
+		}
+		return fl.seek(offset)
+	}
+
+	return fl.seek(fi.Size())
+}
+
+func (fl *follower) seek(offset int64) error {
+	if _, err := fl.f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	fl.r.Reset(fl.f)
+	fl.offset = offset
+
+	return nil
+}
+
+// readLines sends every complete line available, a trailing partial line is
+// left for the next call.
+func (fl *follower) readLines(ctx context.Context, lines chan<- string) (int, error) {
+	var n int
+
+	for {
+		line, err := fl.r.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			// rewind over the partial line so it is read whole next time
+			if line != "" {
+				if err := fl.seek(fl.offset); err != nil {
+					return n, err
+				}
+			}
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return n, nil
+		case lines <- strings.TrimRight(line, "\r\n"):
+		}
+
+		fl.offset += int64(len(line))
+		n++
+	}
+}
+
+// checkRotation reports whether the path now refers to a different inode,
+// and rewinds when the file has been truncated below our offset.
+func (fl *follower) checkRotation() (bool, error) {
+	fi, err := os.Stat(fl.fileName)
+	if os.IsNotExist(err) {
+		// renamed away and not yet recreated
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if fileInode(fi) != fl.inode {
+		return true, nil
+	}
+
+	if fi.Size() < fl.offset {
+		return false, fl.seek(0)
+	}
+
+	return false, nil
+}
+
+func (fl *follower) saveOffset() error {
+	tmp := fl.opts.OffsetFile + ".tmp"
+
+	err := os.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", fl.inode, fl.offset)), 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, fl.opts.OffsetFile)
+}
+
+func loadFollowOffset(fileName string) (uint64, int64, bool) {
+	b, err := os.ReadFile(filepath.Clean(fileName))
+	if err != nil {
+		return 0, 0, false
+	}
+
+	fields := strings.Fields(string(b))
+	if len(fields) != 2 {
+		return 0, 0, false
+	}
+
+	inode, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	offset, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || offset < 0 {
+		return 0, 0, false
+	}
+
+	return inode, offset, true
+}
@@ -0,0 +1,211 @@
+package fileutils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func receiveLines(t *testing.T, lines <-chan string, n int) []string {
+	t.Helper()
+
+	var received []string
+	timeout := time.After(5 * time.Second)
+
+	for len(received) < n {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("lines closed after %v", received)
+			}
+			received = append(received, line)
+		case <-timeout:
+			t.Fatalf("timed out waiting for lines, got %v", received)
+		}
+	}
+
+	return received
+}
+
+func appendLines(t *testing.T, fileName string, lines ...string) {
+	t.Helper()
+
+	f, err := GetFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, line := range lines {
+		err := WriteLine(f, line+"\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFollow(t *testing.T) {
+	targetFile := filepath.Join(t.TempDir(), "app.log")
+	appendLines(t, targetFile, "old 1", "old 2", "old 3")
+
+	tests := map[string]struct {
+		opts     *FollowOptions
+		expected []string
+	}{
+		"from end": {
+			opts:     &FollowOptions{PollInterval: 10 * time.Millisecond},
+			expected: []string{"new"},
+		},
+		"from last lines": {
+			opts:     &FollowOptions{FromLines: 2, PollInterval: 10 * time.Millisecond},
+			expected: []string{"old 2", "old 3", "new"},
+		},
+	}
+
+	for name, tt := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		lines, errs := Follow(ctx, targetFile, tt.opts)
+
+		// give the follower time to seek before appending
+		time.Sleep(50 * time.Millisecond)
+		appendLines(t, targetFile, "new")
+
+		actual := receiveLines(t, lines, len(tt.expected))
+		cancel()
+
+		for err := range errs {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%s: expected %v, got %v", name, tt.expected, actual)
+		}
+
+		err := os.WriteFile(targetFile, []byte("old 1\nold 2\nold 3\n"), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFollowPartialLine(t *testing.T) {
+	targetFile := filepath.Join(t.TempDir(), "app.log")
+	appendLines(t, targetFile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lines, _ := Follow(ctx, targetFile, &FollowOptions{PollInterval: 10 * time.Millisecond})
+	time.Sleep(50 * time.Millisecond)
+
+	f, err := GetFile(targetFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	err = WriteLine(f, "hel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	err = WriteLine(f, "lo\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := receiveLines(t, lines, 1)
+	if actual[0] != "hello" {
+		t.Errorf("expected hello, got %q", actual[0])
+	}
+}
+
+func TestFollowTruncateAndRotate(t *testing.T) {
+	dir := t.TempDir()
+	targetFile := filepath.Join(dir, "app.log")
+	appendLines(t, targetFile, "first")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lines, errs := Follow(ctx, targetFile, &FollowOptions{FromLines: 1, PollInterval: 10 * time.Millisecond})
+	receiveLines(t, lines, 1)
+
+	// truncate in place
+	err := os.Truncate(targetFile, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	appendLines(t, targetFile, "after truncate")
+
+	actual := receiveLines(t, lines, 1)
+	if actual[0] != "after truncate" {
+		t.Errorf("expected line after truncate, got %q", actual[0])
+	}
+
+	// rename and recreate
+	err = os.Rename(targetFile, targetFile+".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendLines(t, targetFile+".1", "late write to old file")
+	appendLines(t, targetFile, "after rotate")
+
+	actual = receiveLines(t, lines, 2)
+	expected := []string{"late write to old file", "after rotate"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	cancel()
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestFollowOffsetFile(t *testing.T) {
+	dir := t.TempDir()
+	targetFile := filepath.Join(dir, "app.log")
+	offsetFile := filepath.Join(dir, "app.offset")
+	opts := &FollowOptions{OffsetFile: offsetFile, FromLines: 10, PollInterval: 10 * time.Millisecond}
+
+	appendLines(t, targetFile, "one", "two")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lines, errs := Follow(ctx, targetFile, opts)
+	receiveLines(t, lines, 2)
+	cancel()
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	// written while nobody was following
+	appendLines(t, targetFile, "three")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	lines, _ = Follow(ctx, targetFile, opts)
+	actual := receiveLines(t, lines, 1)
+
+	if actual[0] != "three" {
+		t.Errorf("expected to resume at three, got %q", actual[0])
+	}
+}
+
+func TestFollowMissingFile(t *testing.T) {
+	lines, errs := Follow(context.Background(), filepath.Join(t.TempDir(), "nofile.log"), nil)
+
+	for range lines {
+		t.Errorf("expected no lines")
+	}
+
+	if err := <-errs; err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
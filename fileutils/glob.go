@@ -0,0 +1,425 @@
+package fileutils
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Glob patterns are slash separated and extend path.Match with:
+//
+//	**      any number of folders, including none, when it is a whole component
+//	{a,b}   either alternative, alternatives may nest and contain slashes
+//	[!a-z]  a negated character class, the same as [^a-z]
+//	!pat    a leading ! negates the whole pattern
+//
+// Patterns are matched against paths relative to the root being searched.
+
+type globPattern struct {
+	negate bool
+	// one list of path components per brace alternative
+	alts [][]string
+}
+
+type globMatcher struct {
+	include []globPattern
+	exclude []globPattern
+}
+
+// Match reports whether name matches pattern. It uses the same matcher as
+// Glob, so name should be a slash separated relative path.
+func Match(pattern, name string) (bool, error) {
+	var funcName string = "Match"
+
+	p, err := compileGlob(pattern)
+	if err != nil {
+		return false, newTargetError(funcName, name, pattern, "invalid pattern", err)
+	}
+
+	return p.match(globParts(name)) != p.negate, nil
+}
+
+// Glob returns the files and folders under root that match any of patterns
+// and none of the negated ones, in lexical order. Folders that no pattern
+// could match below, or that a negated pattern matches, are not walked.
+func Glob(root string, patterns ...string) ([]string, error) {
+	var funcName string = "Glob"
+
+	m, err := newGlobMatcher(patterns)
+	if err != nil {
+		return nil, newError(funcName, root, "invalid pattern", err)
+	}
+
+	if !FolderExists(root) {
+		return nil, newError(funcName, root, "", ErrNotExist)
+	}
+
+	if !IsFolder(root) {
+		return nil, newError(funcName, root, "", ErrNotFolder)
+	}
+
+	var matches []string
+
+	err = filepath.WalkDir(root, func(s string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+
+		rel, err := filepath.Rel(root, s)
+		if err != nil {
+			return err
+		}
+
+		if m.visit(filepath.ToSlash(rel)) {
+			matches = append(matches, s)
+		}
+
+		if d.IsDir() && s != root && m.prune(filepath.ToSlash(rel)) {
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, newError(funcName, root, "error walking target", err)
+	}
+
+	return matches, nil
+}
+
+// GlobFS is Glob for an fs.FS, root is a path within fsys.
+func GlobFS(fsys fs.FS, root string, patterns ...string) ([]string, error) {
+	var funcName string = "GlobFS"
+
+	m, err := newGlobMatcher(patterns)
+	if err != nil {
+		return nil, newError(funcName, root, "invalid pattern", err)
+	}
+
+	var matches []string
+
+	err = fs.WalkDir(fsys, root, func(s string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(s, root), "/")
+		if root == "." {
+			rel = s
+		}
+
+		if m.visit(rel) {
+			matches = append(matches, s)
+		}
+
+		if d.IsDir() && s != root && m.prune(rel) {
+			return fs.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, newError(funcName, root, "error walking target", err)
+	}
+
+	return matches, nil
+}
+
+func newGlobMatcher(patterns []string) (*globMatcher, error) {
+	m := &globMatcher{}
+
+	for _, pattern := range patterns {
+		p, err := compileGlob(pattern)
+		if err != nil {
+			return nil, newTargetError("newGlobMatcher", "", pattern, "", err)
+		}
+
+		if p.negate {
+			m.exclude = append(m.exclude, p)
+		} else {
+			m.include = append(m.include, p)
+		}
+	}
+
+	return m, nil
+}
+
+// visit reports whether rel, the root itself when empty or ".", is a match.
+func (m *globMatcher) visit(rel string) bool {
+	parts := globParts(rel)
+	if len(parts) == 0 {
+		return false
+	}
+
+	for _, p := range m.exclude {
+		if p.match(parts) {
+			return false
+		}
+	}
+
+	for _, p := range m.include {
+		if p.match(parts) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// prune reports whether nothing below the folder rel can match.
+func (m *globMatcher) prune(rel string) bool {
+	parts := globParts(rel)
+
+	for _, p := range m.exclude {
+		if p.match(parts) {
+			return true
+		}
+	}
+
+	for _, p := range m.include {
+		if p.matchBelow(parts) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p globPattern) match(parts []string) bool {
+	for _, alt := range p.alts {
+		if matchGlobParts(alt, parts) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p globPattern) matchBelow(parts []string) bool {
+	for _, alt := range p.alts {
+		if matchGlobPrefix(alt, parts) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func compileGlob(pattern string) (globPattern, error) {
+	var p globPattern
+
+	if strings.HasPrefix(pattern, "!") {
+		p.negate = true
+		pattern = pattern[1:]
+	}
+
+	alts, err := expandBraces(pattern)
+	if err != nil {
+		return p, err
+	}
+
+	for _, alt := range alts {
+		var comps []string
+		for _, c := range globParts(alt) {
+			// a run of ** matches the same as one
+			if c == "**" && len(comps) > 0 && comps[len(comps)-1] == "**" {
+				continue
+			}
+
+			c = negateClasses(c)
+			if _, err := path.Match(c, ""); err != nil {
+				return p, err
+			}
+
+			comps = append(comps, c)
+		}
+
+		p.alts = append(p.alts, comps)
+	}
+
+	return p, nil
+}
+
+func matchGlobParts(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			if len(pat) == 1 {
+				return true
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchGlobParts(pat[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(parts) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(pat[0], parts[0]); !ok {
+			return false
+		}
+
+		pat, parts = pat[1:], parts[1:]
+	}
+
+	return len(parts) == 0
+}
+
+// matchGlobPrefix reports whether the folder parts could contain a match for
+// pat.
+func matchGlobPrefix(pat, parts []string) bool {
+	for len(parts) > 0 {
+		if len(pat) == 0 {
+			return false
+		}
+
+		if pat[0] == "**" {
+			return true
+		}
+
+		if ok, _ := path.Match(pat[0], parts[0]); !ok {
+			return false
+		}
+
+		pat, parts = pat[1:], parts[1:]
+	}
+
+	return len(pat) > 0
+}
+
+// expandBraces returns every alternative of the {a,b} groups in pattern.
+func expandBraces(pattern string) ([]string, error) {
+	start, end := -1, -1
+	var commas []int
+	depth := 0
+
+	for i := 0; i < len(pattern) && end < 0; i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '[':
+			// braces and commas in a character class are literal
+			for i++; i < len(pattern) && pattern[i] != ']'; i++ {
+				if pattern[i] == '\\' {
+					i++
+				}
+			}
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			if depth == 0 {
+				return nil, path.ErrBadPattern
+			}
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, path.ErrBadPattern
+	}
+	if start < 0 {
+		return []string{pattern}, nil
+	}
+
+	prefix, suffix := pattern[:start], pattern[end+1:]
+	bounds := append(append([]int{start}, commas...), end)
+
+	var out []string
+	for i := 0; i < len(bounds)-1; i++ {
+		alts, err := expandBraces(prefix + pattern[bounds[i]+1:bounds[i+1]] + suffix)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, alts...)
+	}
+
+	return out, nil
+}
+
+// negateClasses rewrites [!...] as the [^...] that path.Match understands.
+func negateClasses(c string) string {
+	if !strings.Contains(c, "[!") {
+		return c
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(c); i++ {
+		switch {
+		case c[i] == '\\' && i+1 < len(c):
+			b.WriteString(c[i : i+2])
+			i++
+		case c[i] == '[' && i+1 < len(c) && c[i+1] == '!':
+			b.WriteString("[^")
+			i++
+		default:
+			b.WriteByte(c[i])
+		}
+	}
+
+	return b.String()
+}
+
+func globParts(name string) []string {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		return nil
+	}
+
+	return strings.Split(name, "/")
+}
+
+// walkMatching calls fn for each regular file below root that globs matches,
+// or every regular file when globs is nil, with its slash separated path
+// relative to root. root may also be a single file, which is always passed
+// to fn, relative to its folder.
+func walkMatching(root string, globs *globMatcher, fn func(fileName, rel string) error) error {
+	fi, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+
+	base := root
+	if !fi.IsDir() {
+		base = filepath.Dir(root)
+	}
+
+	return filepath.WalkDir(root, func(s string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+
+		rel, err := filepath.Rel(base, s)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if s != root && globs != nil && globs.prune(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() || (globs != nil && s != root && !globs.visit(rel)) {
+			return nil
+		}
+
+		return fn(s, rel)
+	})
+}
@@ -0,0 +1,203 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*.csv", "a.csv", true},
+		{"*.csv", "data/a.csv", false},
+		{"data/*.csv", "data/a.csv", true},
+		{"**/*.csv", "a.csv", true},
+		{"**/*.csv", "data/x/y/a.csv", true},
+		{"data/**", "data", true},
+		{"data/**", "data/x/y", true},
+		{"data/**/a.csv", "data/a.csv", true},
+		{"data/**/a.csv", "data/x/y/a.csv", true},
+		{"data/**/a.csv", "other/a.csv", false},
+		{"data/**/**/a.csv", "data/x/a.csv", true},
+		{"data/**/{2023,2024}/*.csv", "data/x/2024/a.csv", true},
+		{"data/**/{2023,2024}/*.csv", "data/2023/a.csv", true},
+		{"data/**/{2023,2024}/*.csv", "data/x/2022/a.csv", false},
+		{"*.{csv,json}", "a.json", true},
+		{"{a/b,c}/*.txt", "a/b/x.txt", true},
+		{"{a,b{c,d}}.txt", "bd.txt", true},
+		{"{a,b{c,d}}.txt", "b.txt", false},
+		{"file[0-9].txt", "file5.txt", true},
+		{"file[!0-9].txt", "file5.txt", false},
+		{"file[!0-9].txt", "filex.txt", true},
+		{"file[^0-9].txt", "filex.txt", true},
+		{"file[{,}].txt", "file,.txt", true},
+		{"?.txt", "a.txt", true},
+		{`\*.txt`, "*.txt", true},
+		{`\*.txt`, "a.txt", false},
+		{"!*.csv", "a.csv", false},
+		{"!*.csv", "a.txt", true},
+		{"/data/*.csv", "data/a.csv", true},
+	}
+
+	for _, tt := range tests {
+		actual, err := Match(tt.pattern, tt.name)
+		if err != nil {
+			t.Errorf("%v %v: %v", tt.pattern, tt.name, err)
+			continue
+		}
+
+		if actual != tt.expected {
+			t.Errorf("%v %v: expected %v, got %v", tt.pattern, tt.name, tt.expected, actual)
+		}
+	}
+
+	for _, pattern := range []string{"{a,b", "a}", "[a-", "a/{b,[}"} {
+		if _, err := Match(pattern, "a"); !errors.Is(err, path.ErrBadPattern) {
+			t.Errorf("%v: expected ErrBadPattern, got %v", pattern, err)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+
+	files := []string{
+		"data/2023/a.csv",
+		"data/2024/b.csv",
+		"data/2024/b.json",
+		"data/x/2024/c.csv",
+		"data/tmp/2024/d.csv",
+		"other/2024/e.csv",
+		"top.csv",
+	}
+	for _, f := range files {
+		fileName := filepath.Join(dir, f)
+		if err := MkDir(filepath.Dir(fileName)); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fileName, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]struct {
+		patterns []string
+		expected []string
+	}{
+		"doublestar and braces": {
+			patterns: []string{"data/**/{2023,2024}/*.csv"},
+			expected: []string{"data/2023/a.csv", "data/2024/b.csv", "data/tmp/2024/d.csv", "data/x/2024/c.csv"},
+		},
+		"negated folder": {
+			patterns: []string{"data/**/*.csv", "!**/tmp"},
+			expected: []string{"data/2023/a.csv", "data/2024/b.csv", "data/x/2024/c.csv"},
+		},
+		"folders": {
+			patterns: []string{"*/2024"},
+			expected: []string{"data/2024", "other/2024"},
+		},
+		"several patterns": {
+			patterns: []string{"*.csv", "data/2024/*.json"},
+			expected: []string{"data/2024/b.json", "top.csv"},
+		},
+		"no match": {
+			patterns: []string{"nothing/**"},
+		},
+	}
+
+	for name, tt := range tests {
+		actual, err := Glob(dir, tt.patterns...)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		var rel []string
+		for _, a := range actual {
+			r, err := filepath.Rel(dir, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rel = append(rel, filepath.ToSlash(r))
+		}
+
+		if !reflect.DeepEqual(tt.expected, rel) {
+			t.Errorf("%s: expected %v, got %v", name, tt.expected, rel)
+		}
+	}
+
+	if _, err := Glob(filepath.Join(dir, "nofolder"), "*"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+
+	if _, err := Glob(dir, "{a"); !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("expected ErrBadPattern, got %v", err)
+	}
+}
+
+func TestGlobPrune(t *testing.T) {
+	m, err := newGlobMatcher([]string{"data/**/{2023,2024}/*.csv", "!**/tmp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"data":           false,
+		"data/x/y":       false,
+		"other":          true,
+		"data/tmp":       true,
+		"data/2024/deep": false,
+	}
+
+	for rel, expected := range tests {
+		if actual := m.prune(rel); actual != expected {
+			t.Errorf("%v: expected prune %v, got %v", rel, expected, actual)
+		}
+	}
+
+	m, err = newGlobMatcher([]string{"a/b/*.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for rel, expected := range map[string]bool{"a": false, "a/b": false, "a/c": true, "a/b/c": true} {
+		if actual := m.prune(rel); actual != expected {
+			t.Errorf("%v: expected prune %v, got %v", rel, expected, actual)
+		}
+	}
+}
+
+func TestGlobFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root/a/x.csv":   {},
+		"root/a/b/y.csv": {},
+		"root/c.txt":     {},
+	}
+
+	actual, err := GlobFS(fsys, "root", "**/*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"root/a/b/y.csv", "root/a/x.csv"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	actual, err = GlobFS(fsys, ".", "root/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []string{"root/c.txt"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
@@ -3,7 +3,6 @@ package fileutils
 import (
 	"context"
 	"errors"
-	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -24,6 +23,8 @@ func Request(url, verb string, payload io.Reader, timeout *time.Duration) (*http
 }
 
 func sendWithContext(url, verb string, payload io.Reader, timeout *time.Duration) (*http.Response, context.CancelFunc, error) {
+	var funcName string = "Request"
+
 	client := http.Client{
 		Transport: &http.Transport{
 			DialContext: (&net.Dialer{
@@ -36,16 +37,16 @@ func sendWithContext(url, verb string, payload io.Reader, timeout *time.Duration
 
 	request, err := http.NewRequestWithContext(ctx, verb, url, payload)
 	if err != nil {
-		return nil, cancel, err
+		return nil, cancel, newError(funcName, url, "error creating request", err)
 	}
 
 	response, err := client.Do(request)
 
 	var ne net.Error
 	if errors.As(err, &ne) && ne.Timeout() {
-		return nil, cancel, fmt.Errorf("request timeout: %v", err.Error())
+		return nil, cancel, newError(funcName, url, "request timeout", err)
 	} else if err != nil {
-		return nil, cancel, fmt.Errorf("request error: %v", err.Error())
+		return nil, cancel, newError(funcName, url, "request error", err)
 	}
 
 	return response, cancel, nil
@@ -57,7 +58,7 @@ func DownloadFileToPath(url, filePath string) error {
 	// Create the file
 	out, err := os.Create(filePath)
 	if err != nil {
-		return fmt.Errorf("%v.%v: error creating file [%v], [%v]", packageName, funcName, filePath, err.Error())
+		return newError(funcName, filePath, "error creating file", err)
 	}
 	defer out.Close()
 
@@ -65,19 +66,19 @@ func DownloadFileToPath(url, filePath string) error {
 	resp, cancel, err := Request(url, http.MethodGet, nil, nil)
 	defer cancel()
 	if err != nil {
-		return fmt.Errorf("%v.%v: error downloading file [%v], [%v]", packageName, funcName, url, err.Error())
+		return newError(funcName, url, "error downloading file", err)
 	}
 	defer resp.Body.Close()
 
 	// Check server response
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%v.%v: bad return status: %s", packageName, funcName, resp.Status)
+		return newTargetError(funcName, url, resp.Status, "", ErrBadStatus)
 	}
 
 	// Write the body to file
 	_, err = io.Copy(out, resp.Body)
 	if err != nil {
-		return fmt.Errorf("%v.%v: error writing file [%v], [%v]", packageName, funcName, filePath, err.Error())
+		return newError(funcName, filePath, "error writing file", err)
 	}
 
 	return nil
@@ -0,0 +1,223 @@
+package fileutils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const dataFilePerm = 0644
+
+// ReadJSON decodes the JSON document in fileName into a new T.
+func ReadJSON[T any](fileName string) (T, error) {
+	var funcName string = "ReadJSON"
+
+	var v T
+
+	b, err := os.ReadFile(filepath.Clean(fileName))
+	if err != nil {
+		return v, newError(funcName, fileName, "error reading file", err)
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, newError(funcName, fileName, "error decoding json", err)
+	}
+
+	return v, nil
+}
+
+// WriteJSON encodes v and atomically replaces fileName with it. When pretty is
+// set the output is indented with two spaces.
+func WriteJSON(fileName string, v any, pretty bool) error {
+	var funcName string = "WriteJSON"
+
+	var b []byte
+	var err error
+
+	if pretty {
+		b, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		b, err = json.Marshal(v)
+	}
+	if err != nil {
+		return newError(funcName, fileName, "error encoding json", err)
+	}
+	b = append(b, '\n')
+
+	err = writeFileAtomic(fileName, dataFilePerm, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+	if err != nil {
+		return newError(funcName, fileName, "error writing file", err)
+	}
+
+	return nil
+}
+
+// NDJSONReader decodes newline delimited JSON one record at a time. Blank lines
+// are skipped.
+type NDJSONReader[T any] struct {
+	fileName string
+	f        *os.File
+	r        *bufio.Reader
+	line     int
+}
+
+func NewNDJSONReader[T any](fileName string) (*NDJSONReader[T], error) {
+	var funcName string = "NewNDJSONReader"
+
+	f, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return nil, newError(funcName, fileName, "error opening file", err)
+	}
+
+	return &NDJSONReader[T]{
+		fileName: fileName,
+		f:        f,
+		r:        bufio.NewReader(f),
+	}, nil
+}
+
+// Next returns the next record, or io.EOF once the file is exhausted.
+func (n *NDJSONReader[T]) Next() (T, error) {
+	var funcName string = "NDJSONReader.Next"
+
+	var v T
+
+	for {
+		line, err := n.r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			if errors.Is(err, io.EOF) {
+				return v, io.EOF
+			}
+			return v, newError(funcName, n.fileName, "error reading file", err)
+		}
+		n.line++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if err := json.Unmarshal(line, &v); err != nil {
+			return v, newTargetError(funcName, n.fileName, lineTarget(n.line), "error decoding json", err)
+		}
+
+		return v, nil
+	}
+}
+
+func (n *NDJSONReader[T]) Close() error {
+	return n.f.Close()
+}
+
+// ReadNDJSON calls fn for every record in fileName, stopping at the first
+// error fn returns.
+func ReadNDJSON[T any](fileName string, fn func(v T) error) error {
+	r, err := NewNDJSONReader[T](fileName)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for {
+		v, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+}
+
+// NDJSONWriter appends records to a newline delimited JSON file, creating it
+// when needed.
+type NDJSONWriter[T any] struct {
+	fileName string
+	f        *os.File
+	w        *bufio.Writer
+	enc      *json.Encoder
+}
+
+func NewNDJSONWriter[T any](fileName string) (*NDJSONWriter[T], error) {
+	var funcName string = "NewNDJSONWriter"
+
+	f, err := GetFile(fileName)
+	if err != nil {
+		return nil, newError(funcName, fileName, "error opening file", err)
+	}
+
+	w := bufio.NewWriter(f)
+
+	return &NDJSONWriter[T]{
+		fileName: fileName,
+		f:        f,
+		w:        w,
+		enc:      json.NewEncoder(w),
+	}, nil
+}
+
+func (n *NDJSONWriter[T]) Write(v T) error {
+	var funcName string = "NDJSONWriter.Write"
+
+	if err := n.enc.Encode(v); err != nil {
+		return newError(funcName, n.fileName, "error encoding json", err)
+	}
+
+	return nil
+}
+
+// Flush writes any buffered records to the file.
+func (n *NDJSONWriter[T]) Flush() error {
+	var funcName string = "NDJSONWriter.Flush"
+
+	if err := n.w.Flush(); err != nil {
+		return newError(funcName, n.fileName, "error writing file", err)
+	}
+
+	return nil
+}
+
+func (n *NDJSONWriter[T]) Close() error {
+	err := n.Flush()
+	if cerr := n.f.Close(); err == nil && cerr != nil {
+		err = newError("NDJSONWriter.Close", n.fileName, "error closing file", cerr)
+	}
+
+	return err
+}
+
+// WriteNDJSON atomically replaces fileName with one JSON line per record.
+func WriteNDJSON[T any](fileName string, records []T) error {
+	var funcName string = "WriteNDJSON"
+
+	err := writeFileAtomic(fileName, dataFilePerm, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		for i := range records {
+			if err := enc.Encode(records[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return newError(funcName, fileName, "error writing file", err)
+	}
+
+	return nil
+}
+
+func lineTarget(line int) string {
+	return "line " + strconv.Itoa(line)
+}
@@ -0,0 +1,141 @@
+package fileutils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testRecord struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+func TestJSON(t *testing.T) {
+	dir := t.TempDir()
+
+	expected := map[string]testRecord{
+		"a": {Name: "a", Count: 1, Tags: []string{"x", "y"}},
+		"b": {Name: "b", Count: 2},
+	}
+
+	for _, pretty := range []bool{false, true} {
+		fileName := filepath.Join(dir, "data.json")
+
+		err := WriteJSON(fileName, expected, pretty)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contents, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if indented := strings.Contains(string(contents), "\n  "); indented != pretty {
+			t.Errorf("pretty %v: expected indented %v, got %v", pretty, pretty, indented)
+		}
+
+		actual, err := ReadJSON[map[string]testRecord](fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("pretty %v: expected %v, got %v", pretty, expected, actual)
+		}
+	}
+
+	_, err := ReadJSON[testRecord](filepath.Join(dir, "nofile.json"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	err = os.WriteFile(bad, []byte("{"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadJSON[testRecord](bad)
+	if err == nil {
+		t.Errorf("expected decode error, got nil")
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "data.ndjson")
+
+	expected := []testRecord{
+		{Name: "a", Count: 1},
+		{Name: "b", Count: 2, Tags: []string{"x"}},
+		{Name: "c", Count: 3},
+	}
+
+	err := WriteNDJSON(fileName, expected[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewNDJSONWriter[testRecord](fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range expected[1:] {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// blank lines are skipped
+	err = WriteFile(fileName, "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []testRecord
+	err = ReadNDJSON(fileName, func(r testRecord) error {
+		actual = append(actual, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	err = WriteFile(fileName, "not json\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewNDJSONReader[testRecord](fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for i := 0; i < len(expected); i++ {
+		if _, err := r.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = r.Next()
+	var fe *Error
+	if !errors.As(err, &fe) || fe.Target != "line 5" {
+		t.Errorf("expected decode error on line 5, got %v", err)
+	}
+
+	_, err = r.Next()
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
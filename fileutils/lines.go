@@ -0,0 +1,281 @@
+package fileutils
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// block size used when reading files backwards.
+const lineBlockSize = 64 * 1024
+
+// Tail returns the last n lines of fileName without reading the rest of the
+// file. Line endings are stripped and lines may be any length.
+func Tail(fileName string, n int) ([]string, error) {
+	var funcName string = "Tail"
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return []string{}, newError(funcName, fileName, "error opening file", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return []string{}, newError(funcName, fileName, "error getting file info", err)
+	}
+
+	offset, err := lastLinesOffset(f, fi.Size(), n)
+	if err != nil {
+		return []string{}, newError(funcName, fileName, "error reading file", err)
+	}
+
+	lines := []string{}
+	err = readLines(io.NewSectionReader(f, offset, fi.Size()-offset), func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		return []string{}, newError(funcName, fileName, "error reading file", err)
+	}
+
+	return lines, nil
+}
+
+// ReverseLineReader reads the lines of a file from last to first.
+type ReverseLineReader struct {
+	f     *os.File
+	pos   int64
+	buf   []byte
+	carry []byte
+	first bool
+}
+
+func NewReverseLineReader(fileName string) (*ReverseLineReader, error) {
+	var funcName string = "NewReverseLineReader"
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, newError(funcName, fileName, "error opening file", err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, newError(funcName, fileName, "error getting file info", err)
+	}
+
+	return &ReverseLineReader{
+		f:     f,
+		pos:   fi.Size(),
+		buf:   make([]byte, lineBlockSize),
+		first: true,
+	}, nil
+}
+
+// Line returns the previous line, or io.EOF once the start of the file has
+// been reached.
+func (r *ReverseLineReader) Line() (string, error) {
+	var funcName string = "ReverseLineReader.Line"
+
+	for {
+		if i := bytes.LastIndexByte(r.carry, '\n'); i >= 0 {
+			line := string(r.carry[i+1:])
+			r.carry = r.carry[:i]
+
+			// a final newline does not start an empty last line
+			if r.first && line == "" {
+				r.first = false
+				continue
+			}
+			r.first = false
+
+			return strings.TrimSuffix(line, "\r"), nil
+		}
+
+		if r.pos == 0 {
+			if r.carry == nil {
+				return "", io.EOF
+			}
+
+			line := string(r.carry)
+			r.carry = nil
+			r.first = false
+
+			return strings.TrimSuffix(line, "\r"), nil
+		}
+
+		size := int64(len(r.buf))
+		if r.pos < size {
+			size = r.pos
+		}
+		r.pos -= size
+
+		block := r.buf[:size]
+		if _, err := r.f.ReadAt(block, r.pos); err != nil && !errors.Is(err, io.EOF) {
+			return "", newError(funcName, r.f.Name(), "error reading file", err)
+		}
+
+		carry := make([]byte, 0, len(block)+len(r.carry))
+		carry = append(carry, block...)
+		r.carry = append(carry, r.carry...)
+	}
+}
+
+func (r *ReverseLineReader) Close() error {
+	return r.f.Close()
+}
+
+// LineChunk is a byte range of a file that starts at the beginning of a line
+// and ends just after a newline, or at the end of the file.
+type LineChunk struct {
+	FileName string
+	Offset   int64
+	Size     int64
+}
+
+// LineChunks splits fileName into ranges of roughly chunkSize bytes aligned
+// on line boundaries, so the lines of each range can be processed in
+// parallel.
+func LineChunks(fileName string, chunkSize int64) ([]LineChunk, error) {
+	var funcName string = "LineChunks"
+
+	if chunkSize <= 0 {
+		return []LineChunk{}, newError(funcName, fileName, "chunk size must be positive", ErrInvalid)
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return []LineChunk{}, newError(funcName, fileName, "error opening file", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return []LineChunk{}, newError(funcName, fileName, "error getting file info", err)
+	}
+	size := fi.Size()
+
+	chunks := []LineChunk{}
+	var start int64
+
+	for start < size {
+		end := start + chunkSize
+		if end >= size {
+			end = size
+		} else {
+			end, err = nextLineStart(f, end, size)
+			if err != nil {
+				return []LineChunk{}, newError(funcName, fileName, "error reading file", err)
+			}
+		}
+
+		chunks = append(chunks, LineChunk{FileName: fileName, Offset: start, Size: end - start})
+		start = end
+	}
+
+	return chunks, nil
+}
+
+// ReadLines calls fn for every line in the chunk, stopping at the first error.
+func (c LineChunk) ReadLines(fn func(line string) error) error {
+	var funcName string = "LineChunk.ReadLines"
+
+	f, err := os.Open(c.FileName)
+	if err != nil {
+		return newError(funcName, c.FileName, "error opening file", err)
+	}
+	defer f.Close()
+
+	if err := readLines(io.NewSectionReader(f, c.Offset, c.Size), fn); err != nil {
+		return newError(funcName, c.FileName, "error reading file", err)
+	}
+
+	return nil
+}
+
+// readLines is bufio.Scanner without the token size limit.
+func readLines(r io.Reader, fn func(line string) error) error {
+	br := bufio.NewReaderSize(r, lineBlockSize)
+
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			if ferr := fn(strings.TrimRight(line, "\r\n")); ferr != nil {
+				return ferr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// nextLineStart returns the offset just after the first newline at or after
+// from, or size when there is none.
+func nextLineStart(f io.ReaderAt, from, size int64) (int64, error) {
+	buf := make([]byte, lineBlockSize)
+
+	for from < size {
+		n, err := f.ReadAt(buf, from)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return from + int64(i) + 1, nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		if n == 0 {
+			break
+		}
+		from += int64(n)
+	}
+
+	return size, nil
+}
+
+// lastLinesOffset returns the offset of the start of the last n lines in the
+// first size bytes of f, reading backwards in blocks. A final newline does not
+// count as the start of an extra empty line.
+func lastLinesOffset(f io.ReaderAt, size int64, n int) (int64, error) {
+	if n <= 0 || size == 0 {
+		return size, nil
+	}
+
+	buf := make([]byte, lineBlockSize)
+	end := size
+	found := 0
+
+	for end > 0 {
+		start := end - lineBlockSize
+		if start < 0 {
+			start = 0
+		}
+
+		block := buf[:end-start]
+		if _, err := f.ReadAt(block, start); err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+
+		for i := len(block) - 1; i >= 0; i-- {
+			pos := start + int64(i)
+			if block[i] != '\n' || pos == size-1 {
+				continue
+			}
+
+			found++
+			if found == n {
+				return pos + 1, nil
+			}
+		}
+
+		end = start
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,234 @@
+package fileutils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTail(t *testing.T) {
+	dir := t.TempDir()
+	long := strings.Repeat("x", lineBlockSize*3)
+
+	tests := map[string]struct {
+		content  string
+		n        int
+		expected []string
+	}{
+		"empty": {
+			content:  "",
+			n:        3,
+			expected: []string{},
+		},
+		"fewer lines": {
+			content:  "a\nb\n",
+			n:        3,
+			expected: []string{"a", "b"},
+		},
+		"last two": {
+			content:  "a\nb\nc\n",
+			n:        2,
+			expected: []string{"b", "c"},
+		},
+		"no final newline": {
+			content:  "a\nb\nc",
+			n:        2,
+			expected: []string{"b", "c"},
+		},
+		"crlf": {
+			content:  "a\r\nb\r\n",
+			n:        1,
+			expected: []string{"b"},
+		},
+		"long lines": {
+			content:  "a\n" + long + "\n" + long + "\n",
+			n:        2,
+			expected: []string{long, long},
+		},
+	}
+
+	for name, tt := range tests {
+		targetFile := filepath.Join(dir, name+".txt")
+
+		err := os.WriteFile(targetFile, []byte(tt.content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := Tail(targetFile, tt.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%s: expected %v lines, got %v", name, len(tt.expected), len(actual))
+		}
+	}
+}
+
+func TestReverseLineReader(t *testing.T) {
+	dir := t.TempDir()
+	long := strings.Repeat("y", lineBlockSize+10)
+
+	tests := map[string]struct {
+		content  string
+		expected []string
+	}{
+		"empty": {
+			content: "",
+		},
+		"single newline": {
+			content:  "\n",
+			expected: []string{""},
+		},
+		"lines": {
+			content:  "a\nb\nc\n",
+			expected: []string{"c", "b", "a"},
+		},
+		"no final newline": {
+			content:  "a\nb",
+			expected: []string{"b", "a"},
+		},
+		"blank lines": {
+			content:  "a\n\nb\n",
+			expected: []string{"b", "", "a"},
+		},
+		"across blocks": {
+			content:  "first\n" + long + "\nlast\n",
+			expected: []string{"last", long, "first"},
+		},
+	}
+
+	for name, tt := range tests {
+		targetFile := filepath.Join(dir, name+".txt")
+
+		err := os.WriteFile(targetFile, []byte(tt.content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := NewReverseLineReader(targetFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var actual []string
+		for {
+			line, err := r.Line()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, line)
+		}
+		r.Close()
+
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%s: expected %q, got %q", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestLineChunks(t *testing.T) {
+	targetFile := filepath.Join(t.TempDir(), "data.ndjson")
+
+	var expected []string
+	var b strings.Builder
+	for i := 0; i < 1000; i++ {
+		line := strings.Repeat(strconv.Itoa(i), i%7+1)
+		expected = append(expected, line)
+		b.WriteString(line + "\n")
+	}
+
+	err := os.WriteFile(targetFile, []byte(b.String()), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks, err := LineChunks(targetFile, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total int64
+	for i, c := range chunks {
+		if c.Offset != total {
+			t.Errorf("chunk %v: expected offset %v, got %v", i, total, c.Offset)
+		}
+		total += c.Size
+	}
+
+	if total != int64(b.Len()) {
+		t.Errorf("expected chunks to cover %v bytes, got %v", b.Len(), total)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var actual []string
+
+	for _, c := range chunks {
+		wg.Add(1)
+		go func(c LineChunk) {
+			defer wg.Done()
+			err := c.ReadLines(func(line string) error {
+				mu.Lock()
+				actual = append(actual, line)
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	sort.Strings(expected)
+	sort.Strings(actual)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected chunks to yield every line once")
+	}
+
+	_, err = LineChunks(targetFile, 0)
+	if err == nil {
+		t.Errorf("expected error for zero chunk size, got nil")
+	}
+}
+
+func TestLastLinesOffset(t *testing.T) {
+	tests := map[string]struct {
+		content  string
+		n        int
+		expected int64
+	}{
+		"empty":             {content: "", n: 2, expected: 0},
+		"zero lines":        {content: "a\nb\n", n: 0, expected: 4},
+		"last line":         {content: "a\nb\n", n: 1, expected: 2},
+		"all lines":         {content: "a\nb\n", n: 2, expected: 0},
+		"more than present": {content: "a\nb\n", n: 5, expected: 0},
+		"no final newline":  {content: "a\nb\nc", n: 1, expected: 4},
+	}
+
+	for name, tt := range tests {
+		r := strings.NewReader(tt.content)
+
+		actual, err := lastLinesOffset(r, int64(len(tt.content)), tt.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tt.expected != actual {
+			t.Errorf("%s: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
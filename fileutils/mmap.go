@@ -0,0 +1,258 @@
+package fileutils
+
+import (
+	"io"
+	"os"
+	"runtime/debug"
+	"sync"
+)
+
+// MmapAdvice tells the kernel how a mapping will be read, see madvise(2).
+type MmapAdvice string
+
+const (
+	MmapNormal     MmapAdvice = "normal"
+	MmapSequential MmapAdvice = "sequential"
+	MmapRandom     MmapAdvice = "random"
+	MmapWillNeed   MmapAdvice = "willneed"
+)
+
+// MappedFile is a read-only memory mapping of a whole file. It is an
+// io.Reader, io.ReaderAt and io.Seeker, and ReadAt is safe for concurrent
+// use.
+//
+// The mapping does not follow the file, if it is truncated while mapped the
+// pages past the new end can no longer be read. ReadAt and Read report that as
+// ErrTruncated, direct access through Bytes crashes the program instead.
+type MappedFile struct {
+	name string
+
+	mu     sync.RWMutex
+	data   []byte
+	off    int64
+	closed bool
+}
+
+// MapFile maps the regular file fileName into memory. The file does not need
+// to stay open, the mapping lasts until Close. Empty files cannot be mapped,
+// they give a MappedFile with no data that reads as io.EOF straight away.
+func MapFile(fileName string) (*MappedFile, error) {
+	var funcName string = "MapFile"
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, newError(funcName, fileName, "error opening file", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, newError(funcName, fileName, "error getting file info", err)
+	}
+
+	if !fi.Mode().IsRegular() {
+		return nil, newError(funcName, fileName, "", ErrNotRegular)
+	}
+
+	m := &MappedFile{name: fileName}
+
+	size := fi.Size()
+	if size == 0 {
+		return m, nil
+	}
+
+	if int64(int(size)) != size {
+		return nil, newError(funcName, fileName, "file too large to map", ErrInvalid)
+	}
+
+	m.data, err = mmap(f, int(size))
+	if err != nil {
+		return nil, newError(funcName, fileName, "error mapping file", err)
+	}
+
+	return m, nil
+}
+
+func (m *MappedFile) Name() string {
+	return m.name
+}
+
+// Len returns the size of the file when it was mapped, or 0 once closed.
+func (m *MappedFile) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.data)
+}
+
+// Bytes returns the mapped contents, or nil once closed. The slice must not be
+// written to, and it is not protected by the lock like ReadAt is: using it
+// after Close, or while Close runs in another goroutine, crashes the program.
+func (m *MappedFile) Bytes() []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.data
+}
+
+func (m *MappedFile) ReadAt(p []byte, off int64) (int, error) {
+	var funcName string = "MappedFile.ReadAt"
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return 0, newError(funcName, m.name, "", ErrClosed)
+	}
+
+	if off < 0 {
+		return 0, newError(funcName, m.name, "negative offset", ErrInvalid)
+	}
+
+	n, err := m.readAt(p, off)
+	if err != nil && err != io.EOF {
+		return n, newError(funcName, m.name, "error reading mapping", err)
+	}
+
+	return n, err
+}
+
+func (m *MappedFile) Read(p []byte) (int, error) {
+	var funcName string = "MappedFile.Read"
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return 0, newError(funcName, m.name, "", ErrClosed)
+	}
+
+	n, err := m.readAt(p, m.off)
+	m.off += int64(n)
+	if err != nil && err != io.EOF {
+		return n, newError(funcName, m.name, "error reading mapping", err)
+	}
+
+	return n, err
+}
+
+func (m *MappedFile) Seek(offset int64, whence int) (int64, error) {
+	var funcName string = "MappedFile.Seek"
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return 0, newError(funcName, m.name, "", ErrClosed)
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += m.off
+	case io.SeekEnd:
+		offset += int64(len(m.data))
+	default:
+		return 0, newError(funcName, m.name, "invalid whence", ErrInvalid)
+	}
+
+	if offset < 0 {
+		return 0, newError(funcName, m.name, "negative offset", ErrInvalid)
+	}
+
+	m.off = offset
+
+	return offset, nil
+}
+
+// Advise hints how the mapping is about to be read, so the kernel can read
+// ahead or not. It does not change what is read.
+func (m *MappedFile) Advise(advice MmapAdvice) error {
+	var funcName string = "MappedFile.Advise"
+
+	switch advice {
+	case MmapNormal, MmapSequential, MmapRandom, MmapWillNeed:
+	default:
+		return newTargetError(funcName, m.name, string(advice), "unknown advice", ErrInvalid)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return newError(funcName, m.name, "", ErrClosed)
+	}
+
+	if len(m.data) == 0 {
+		return nil
+	}
+
+	if err := madvise(m.data, advice); err != nil {
+		return newTargetError(funcName, m.name, string(advice), "error advising kernel", err)
+	}
+
+	return nil
+}
+
+// Close unmaps the file. It waits for reads in progress, later calls to any
+// method fail with ErrClosed.
+func (m *MappedFile) Close() error {
+	var funcName string = "MappedFile.Close"
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return newError(funcName, m.name, "", ErrClosed)
+	}
+	m.closed = true
+
+	data := m.data
+	m.data = nil
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err := munmap(data); err != nil {
+		return newError(funcName, m.name, "error unmapping file", err)
+	}
+
+	return nil
+}
+
+func (m *MappedFile) readAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+
+	n, err := copyMapped(p, m.data[off:])
+	if err != nil {
+		return 0, err
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// copyMapped copies src, part of a mapping, into dst. Touching a page past
+// the end of a file that has shrunk raises SIGBUS, which the runtime turns
+// into a recoverable panic while SetPanicOnFault is on.
+func copyMapped(dst, src []byte) (n int, err error) {
+	old := debug.SetPanicOnFault(true)
+	defer func() {
+		debug.SetPanicOnFault(old)
+
+		if r := recover(); r != nil {
+			if _, ok := r.(interface{ Addr() uintptr }); !ok {
+				panic(r)
+			}
+			n, err = 0, ErrTruncated
+		}
+	}()
+
+	return copy(dst, src), nil
+}
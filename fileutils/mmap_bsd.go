@@ -0,0 +1,9 @@
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly
+
+package fileutils
+
+// the syscall package only has madvise on linux, and advice is just a hint,
+// so it is dropped here.
+func madvise(b []byte, advice MmapAdvice) error {
+	return nil
+}
@@ -0,0 +1,16 @@
+package fileutils
+
+import (
+	"syscall"
+)
+
+var madviseFlags = map[MmapAdvice]int{
+	MmapNormal:     syscall.MADV_NORMAL,
+	MmapSequential: syscall.MADV_SEQUENTIAL,
+	MmapRandom:     syscall.MADV_RANDOM,
+	MmapWillNeed:   syscall.MADV_WILLNEED,
+}
+
+func madvise(b []byte, advice MmapAdvice) error {
+	return syscall.Madvise(b, madviseFlags[advice])
+}
@@ -0,0 +1,22 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+
+package fileutils
+
+import (
+	"os"
+)
+
+// memory mapping is only implemented on unix systems, MapFile fails with
+// ErrUnsupported elsewhere except for empty files.
+
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, ErrUnsupported
+}
+
+func munmap(b []byte) error {
+	return ErrUnsupported
+}
+
+func madvise(b []byte, advice MmapAdvice) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,244 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package fileutils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestMapFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := map[string]struct {
+		content []byte
+	}{
+		"empty":      {content: []byte{}},
+		"small":      {content: []byte("hello, world\n")},
+		"multi page": {content: bytes.Repeat([]byte("0123456789abcdef"), 1<<10)},
+	}
+
+	for name, tt := range tests {
+		fileName := filepath.Join(dir, name)
+		if err := os.WriteFile(fileName, tt.content, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := MapFile(fileName)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if m.Len() != len(tt.content) {
+			t.Errorf("%s: expected len %v, got %v", name, len(tt.content), m.Len())
+		}
+		if !bytes.Equal(m.Bytes(), tt.content) {
+			t.Errorf("%s: bytes do not match", name)
+		}
+
+		actual, err := io.ReadAll(m)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(actual, tt.content) {
+			t.Errorf("%s: read does not match", name)
+		}
+
+		r := io.NewSectionReader(m, 0, int64(m.Len()))
+		actual, err = io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(actual, tt.content) {
+			t.Errorf("%s: read at does not match", name)
+		}
+
+		for _, advice := range []MmapAdvice{MmapSequential, MmapRandom, MmapWillNeed, MmapNormal} {
+			if err := m.Advise(advice); err != nil {
+				t.Errorf("%s: %s: %v", name, advice, err)
+			}
+		}
+
+		if err := m.Close(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestMappedFileReadAt(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(fileName, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := MapFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	tests := map[string]struct {
+		off      int64
+		size     int
+		expected string
+		err      error
+	}{
+		"start":       {off: 0, size: 4, expected: "0123"},
+		"middle":      {off: 3, size: 4, expected: "3456"},
+		"to end":      {off: 6, size: 4, expected: "6789"},
+		"past end":    {off: 8, size: 4, expected: "89", err: io.EOF},
+		"at end":      {off: 10, size: 4, err: io.EOF},
+		"beyond end":  {off: 20, size: 4, err: io.EOF},
+		"negative":    {off: -1, size: 4, err: ErrInvalid},
+		"zero length": {off: 2, size: 0, expected: ""},
+	}
+
+	for name, tt := range tests {
+		p := make([]byte, tt.size)
+		n, err := m.ReadAt(p, tt.off)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected error %v, got %v", name, tt.err, err)
+		}
+		if string(p[:n]) != tt.expected {
+			t.Errorf("%s: expected %q, got %q", name, tt.expected, string(p[:n]))
+		}
+	}
+
+	// concurrent readers share the mapping
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+			p := make([]byte, 1)
+			if _, err := m.ReadAt(p, off); err != nil || p[0] != byte('0'+off) {
+				t.Errorf("concurrent %d: got %q %v", off, p, err)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+}
+
+func TestMappedFileSeek(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(fileName, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := MapFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	tests := map[string]struct {
+		offset   int64
+		whence   int
+		expected string
+	}{
+		"start":   {offset: 2, whence: io.SeekStart, expected: "234"},
+		"current": {offset: 1, whence: io.SeekCurrent, expected: "678"},
+		"end":     {offset: -2, whence: io.SeekEnd, expected: "89"},
+	}
+
+	for _, name := range []string{"start", "current", "end"} {
+		tt := tests[name]
+		if _, err := m.Seek(tt.offset, tt.whence); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		p := make([]byte, 3)
+		n, _ := m.Read(p)
+		if string(p[:n]) != tt.expected {
+			t.Errorf("%s: expected %q, got %q", name, tt.expected, string(p[:n]))
+		}
+	}
+
+	if _, err := m.Seek(-1, io.SeekStart); !errors.Is(err, ErrInvalid) {
+		t.Errorf("negative: expected %v, got %v", ErrInvalid, err)
+	}
+}
+
+func TestMappedFileErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := MapFile(filepath.Join(dir, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing: expected %v, got %v", os.ErrNotExist, err)
+	}
+	if _, err := MapFile(dir); !errors.Is(err, ErrNotRegular) {
+		t.Errorf("folder: expected %v, got %v", ErrNotRegular, err)
+	}
+
+	fileName := filepath.Join(dir, "data")
+	if err := os.WriteFile(fileName, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := MapFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Advise("sometimes"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("advice: expected %v, got %v", ErrInvalid, err)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.ReadAt(make([]byte, 1), 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("read at: expected %v, got %v", ErrClosed, err)
+	}
+	if _, err := m.Read(make([]byte, 1)); !errors.Is(err, ErrClosed) {
+		t.Errorf("read: expected %v, got %v", ErrClosed, err)
+	}
+	if err := m.Advise(MmapRandom); !errors.Is(err, ErrClosed) {
+		t.Errorf("advise: expected %v, got %v", ErrClosed, err)
+	}
+	if err := m.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("close: expected %v, got %v", ErrClosed, err)
+	}
+	if m.Bytes() != nil {
+		t.Errorf("bytes: expected nil after close")
+	}
+}
+
+func TestMappedFileTruncated(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "data")
+
+	pageSize := os.Getpagesize()
+	content := bytes.Repeat([]byte("x"), 4*pageSize)
+	if err := os.WriteFile(fileName, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := MapFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if err := os.Truncate(fileName, int64(pageSize)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first page is still backed by the file
+	p := make([]byte, 16)
+	if _, err := m.ReadAt(p, 0); err != nil {
+		t.Errorf("first page: expected no error, got %v", err)
+	}
+
+	if _, err := m.ReadAt(p, int64(2*pageSize)); !errors.Is(err, ErrTruncated) {
+		t.Errorf("past end: expected %v, got %v", ErrTruncated, err)
+	}
+
+	if _, err := io.ReadAll(m); !errors.Is(err, ErrTruncated) {
+		t.Errorf("read all: expected %v, got %v", ErrTruncated, err)
+	}
+}
@@ -0,0 +1,444 @@
+package fileutils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type LineEnding string
+
+const (
+	LineEndingLF   LineEnding = "lf"
+	LineEndingCRLF LineEnding = "crlf"
+)
+
+type BOMPolicy string
+
+const (
+	BOMStrip BOMPolicy = "strip"
+	BOMAdd   BOMPolicy = "add"
+)
+
+type TextEncoding string
+
+const (
+	// UTF-16 when the file starts with a UTF-16 byte order mark, otherwise
+	// UTF-8 when valid, falling back to Windows-1252
+	EncodingAuto        TextEncoding = "auto"
+	EncodingUTF8        TextEncoding = "utf-8"
+	EncodingLatin1      TextEncoding = "latin1"
+	EncodingWindows1252 TextEncoding = "windows-1252"
+	// UTF-16 without a byte order mark is read as little endian
+	EncodingUTF16   TextEncoding = "utf-16"
+	EncodingUTF16LE TextEncoding = "utf-16le"
+	EncodingUTF16BE TextEncoding = "utf-16be"
+)
+
+// Changes reported by Normalize.
+const (
+	NormalizedEncoding      = "encoding"
+	NormalizedBOM           = "bom"
+	NormalizedLineEndings   = "line endings"
+	NormalizedTrailingSpace = "trailing whitespace"
+	NormalizedFinalNewline  = "final newline"
+)
+
+// NormalizeOptions selects what Normalize changes, zero values leave that
+// aspect of a file alone.
+type NormalizeOptions struct {
+	// convert files from Encoding to UTF-8. Files starting with a UTF-16 or
+	// UTF-32 byte order mark are skipped unless Encoding is auto or UTF-16,
+	// and UTF-32 is always skipped
+	Encoding TextEncoding
+	BOM      BOMPolicy
+	// convert CRLF, CR and LF line endings to LineEnding
+	LineEnding LineEnding
+	// remove spaces and tabs at the end of lines
+	TrimTrailingSpace bool
+	// end non empty files with a line ending
+	FinalNewline bool
+	// only normalize files matching one of these Glob patterns, relative to
+	// the root, all files when empty
+	Include []string
+	// skip files larger than MaxFileSize bytes, zero means no limit
+	MaxFileSize int64
+	// report the files that would change without writing them, for use as a
+	// CI check
+	CheckOnly bool
+}
+
+type NormalizeResult struct {
+	Path string
+	// what changed, NormalizedEncoding, NormalizedLineEndings etc
+	Changes []string
+}
+
+const utf8BOM = "\ufeff"
+
+// Normalize rewrites the text files below root, which may also be a single
+// file, as set out in opts, skipping binary files. Each changed file is
+// rewritten atomically with its permissions kept. It returns a result for
+// every file that changed, or would with CheckOnly, and stops at the first
+// file that cannot be normalized.
+func Normalize(root string, opts *NormalizeOptions) ([]NormalizeResult, error) {
+	var funcName string = "Normalize"
+
+	if opts == nil {
+		opts = &NormalizeOptions{}
+	}
+
+	var globs *globMatcher
+	if len(opts.Include) > 0 {
+		var err error
+		globs, err = newGlobMatcher(opts.Include)
+		if err != nil {
+			return nil, newError(funcName, root, "invalid include pattern", err)
+		}
+	}
+
+	var results []NormalizeResult
+
+	err := walkMatching(root, globs, func(fileName, rel string) error {
+		result, err := normalizeFile(fileName, opts)
+		if err != nil {
+			return newError(funcName, fileName, "error normalizing file", err)
+		}
+		if len(result.Changes) > 0 {
+			results = append(results, result)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return results, newError(funcName, root, "error walking target", err)
+	}
+
+	return results, nil
+}
+
+// NormalizeText applies opts to b, returning the UTF-8 result and what
+// changed. Include, MaxFileSize and CheckOnly are ignored.
+func NormalizeText(b []byte, opts *NormalizeOptions) (string, []string, error) {
+	var funcName string = "NormalizeText"
+
+	if opts == nil {
+		opts = &NormalizeOptions{}
+	}
+
+	if hasWideBOM(b) && !isUTF16(b, opts.Encoding) {
+		return "", nil, newError(funcName, "", "utf-16 or utf-32 text needs an Encoding", ErrInvalid)
+	}
+
+	s, changes, err := normalizeText(b, opts)
+	if err != nil {
+		return "", nil, newError(funcName, "", "error normalizing text", err)
+	}
+
+	return s, changes, nil
+}
+
+func normalizeFile(fileName string, opts *NormalizeOptions) (NormalizeResult, error) {
+	result := NormalizeResult{Path: fileName}
+
+	fi, err := os.Stat(fileName)
+	if err != nil {
+		return result, err
+	}
+
+	if opts.MaxFileSize > 0 && fi.Size() > opts.MaxFileSize {
+		return result, nil
+	}
+
+	b, err := os.ReadFile(filepath.Clean(fileName))
+	if err != nil {
+		return result, err
+	}
+
+	// UTF-16 is full of NULs, so only sniff content that should be 8 bit.
+	// Wide text that Encoding does not say how to read is left alone, it
+	// would be mangled by 8 bit line ending and whitespace changes
+	if !isUTF16(b, opts.Encoding) {
+		if hasWideBOM(b) || detectType(sniffHead(b)).Binary {
+			return result, nil
+		}
+	}
+
+	after, changes, err := normalizeText(b, opts)
+	if err != nil {
+		return result, err
+	}
+
+	if len(changes) == 0 {
+		return result, nil
+	}
+	result.Changes = changes
+
+	if opts.CheckOnly {
+		return result, nil
+	}
+
+	err = writeFileAtomic(fileName, fi.Mode().Perm(), func(w io.Writer) error {
+		_, err := io.WriteString(w, after)
+		return err
+	})
+
+	return result, err
+}
+
+func normalizeText(b []byte, opts *NormalizeOptions) (string, []string, error) {
+	var changes []string
+
+	s := string(b)
+	if opts.Encoding != "" {
+		decoded, err := decodeText(b, opts.Encoding)
+		if err != nil {
+			return "", nil, err
+		}
+		if decoded != s && decoded != strings.TrimPrefix(s, utf8BOM) {
+			changes = append(changes, NormalizedEncoding)
+		}
+		// a UTF-16 byte order mark becomes a UTF-8 one
+		s = decoded
+	}
+
+	hasBOM := strings.HasPrefix(s, utf8BOM)
+	s = strings.TrimPrefix(s, utf8BOM)
+
+	lines := textLines(s)
+	var endingsChanged, trimmed, finalAdded bool
+
+	target := lineEndingString(opts.LineEnding)
+	if target != "" {
+		for i := range lines {
+			if lines[i].ending != "" && lines[i].ending != target {
+				lines[i].ending = target
+				endingsChanged = true
+			}
+		}
+	}
+
+	if opts.TrimTrailingSpace {
+		for i := range lines {
+			t := strings.TrimRight(lines[i].text, " \t")
+			if t != lines[i].text {
+				lines[i].text = t
+				trimmed = true
+			}
+		}
+	}
+
+	if opts.FinalNewline && len(lines) > 0 {
+		last := &lines[len(lines)-1]
+		if last.ending == "" && last.text != "" {
+			last.ending = target
+			if target == "" {
+				last.ending = dominantEnding(lines)
+			}
+			finalAdded = true
+		}
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s) + len(utf8BOM))
+
+	switch {
+	case opts.BOM == BOMAdd && !hasBOM:
+		changes = append(changes, NormalizedBOM)
+		sb.WriteString(utf8BOM)
+	case opts.BOM == BOMStrip && hasBOM:
+		changes = append(changes, NormalizedBOM)
+	case opts.BOM != BOMStrip && hasBOM:
+		sb.WriteString(utf8BOM)
+	}
+
+	for _, l := range lines {
+		sb.WriteString(l.text)
+		sb.WriteString(l.ending)
+	}
+
+	if endingsChanged {
+		changes = append(changes, NormalizedLineEndings)
+	}
+	if trimmed {
+		changes = append(changes, NormalizedTrailingSpace)
+	}
+	if finalAdded {
+		changes = append(changes, NormalizedFinalNewline)
+	}
+
+	return sb.String(), changes, nil
+}
+
+type textLine struct {
+	text   string
+	ending string
+}
+
+// textLines splits s into lines ending in CRLF, CR or LF, the last line may
+// have no ending.
+func textLines(s string) []textLine {
+	var lines []textLine
+
+	for s != "" {
+		i := strings.IndexAny(s, "\r\n")
+		if i < 0 {
+			lines = append(lines, textLine{text: s})
+			break
+		}
+
+		n := 1
+		if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+			n = 2
+		}
+
+		lines = append(lines, textLine{text: s[:i], ending: s[i : i+n]})
+		s = s[i+n:]
+	}
+
+	return lines
+}
+
+func lineEndingString(e LineEnding) string {
+	switch e {
+	case LineEndingLF:
+		return "\n"
+	case LineEndingCRLF:
+		return "\r\n"
+	}
+
+	return ""
+}
+
+// dominantEnding returns the most common line ending, LF when there are none.
+func dominantEnding(lines []textLine) string {
+	counts := map[string]int{}
+	for _, l := range lines {
+		if l.ending != "" {
+			counts[l.ending]++
+		}
+	}
+
+	best := "\n"
+	for _, e := range []string{"\r\n", "\r"} {
+		if counts[e] > counts[best] {
+			best = e
+		}
+	}
+
+	return best
+}
+
+// isUTF16 reports whether b is to be read as UTF-16, never when it starts
+// with a UTF-32 byte order mark, whatever enc says.
+func isUTF16(b []byte, enc TextEncoding) bool {
+	if bytes.HasPrefix(b, utf32LEBOM) || bytes.HasPrefix(b, utf32BEBOM) {
+		return false
+	}
+
+	switch enc {
+	case EncodingUTF16, EncodingUTF16LE, EncodingUTF16BE:
+		return true
+	case EncodingAuto:
+		return hasWideBOM(b)
+	}
+
+	return false
+}
+
+var (
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+	utf32LEBOM = []byte{0xFF, 0xFE, 0x00, 0x00}
+	utf32BEBOM = []byte{0x00, 0x00, 0xFE, 0xFF}
+)
+
+// hasWideBOM reports whether b starts with a UTF-16 or UTF-32 byte order mark.
+func hasWideBOM(b []byte) bool {
+	return bytes.HasPrefix(b, utf16LEBOM) || bytes.HasPrefix(b, utf16BEBOM) || bytes.HasPrefix(b, utf32BEBOM)
+}
+
+// decodeText returns b decoded from enc as UTF-8. A byte order mark is kept,
+// as U+FEFF, so BOMPolicy can decide what to do with it.
+func decodeText(b []byte, enc TextEncoding) (string, error) {
+	if enc == EncodingAuto {
+		switch {
+		case isUTF16(b, enc):
+			enc = EncodingUTF16
+		case utf8.Valid(b):
+			enc = EncodingUTF8
+		default:
+			enc = EncodingWindows1252
+		}
+	}
+
+	switch enc {
+	case EncodingUTF8:
+		if !utf8.Valid(b) {
+			return "", errors.New("invalid utf-8")
+		}
+		return string(b), nil
+	case EncodingLatin1:
+		return decodeSingleByte(b, nil), nil
+	case EncodingWindows1252:
+		return decodeSingleByte(b, &windows1252), nil
+	case EncodingUTF16, EncodingUTF16LE, EncodingUTF16BE:
+		return decodeUTF16(b, enc)
+	}
+
+	return "", ErrInvalid
+}
+
+func decodeSingleByte(b []byte, high *[32]rune) string {
+	var sb strings.Builder
+	sb.Grow(len(b) + len(b)/8)
+
+	for _, c := range b {
+		switch {
+		case c < 0x80:
+			sb.WriteByte(c)
+		case high != nil && c < 0xA0:
+			sb.WriteRune(high[c-0x80])
+		default:
+			sb.WriteRune(rune(c))
+		}
+	}
+
+	return sb.String()
+}
+
+func decodeUTF16(b []byte, enc TextEncoding) (string, error) {
+	if len(b)%2 != 0 {
+		return "", errors.New("odd length utf-16")
+	}
+
+	bigEndian := enc == EncodingUTF16BE
+	if enc == EncodingUTF16 && bytes.HasPrefix(b, utf16BEBOM) {
+		bigEndian = true
+	}
+
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		if bigEndian {
+			u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		} else {
+			u[i] = uint16(b[2*i+1])<<8 | uint16(b[2*i])
+		}
+	}
+
+	return string(utf16.Decode(u)), nil
+}
+
+// windows1252 maps 0x80 to 0x9F, the bytes where it differs from Latin-1.
+// Unassigned bytes map to the matching C1 control, as browsers do.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
@@ -0,0 +1,315 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		opts     *NormalizeOptions
+		expected string
+		changes  []string
+	}{
+		"nothing to do": {
+			input:    "a\r\nb  \n",
+			opts:     &NormalizeOptions{},
+			expected: "a\r\nb  \n",
+		},
+		"crlf and cr to lf": {
+			input:    "a\r\nb\rc\n",
+			opts:     &NormalizeOptions{LineEnding: LineEndingLF},
+			expected: "a\nb\nc\n",
+			changes:  []string{NormalizedLineEndings},
+		},
+		"lf to crlf": {
+			input:    "a\nb\r\nc",
+			opts:     &NormalizeOptions{LineEnding: LineEndingCRLF},
+			expected: "a\r\nb\r\nc",
+			changes:  []string{NormalizedLineEndings},
+		},
+		"trim trailing whitespace": {
+			input:    "a \t\r\nb\n  \n",
+			opts:     &NormalizeOptions{TrimTrailingSpace: true},
+			expected: "a\r\nb\n\n",
+			changes:  []string{NormalizedTrailingSpace},
+		},
+		"final newline follows the file": {
+			input:    "a\r\nb\r\nc",
+			opts:     &NormalizeOptions{FinalNewline: true},
+			expected: "a\r\nb\r\nc\r\n",
+			changes:  []string{NormalizedFinalNewline},
+		},
+		"final newline uses the target ending": {
+			input:    "a\r\nc",
+			opts:     &NormalizeOptions{FinalNewline: true, LineEnding: LineEndingLF},
+			expected: "a\nc\n",
+			changes:  []string{NormalizedLineEndings, NormalizedFinalNewline},
+		},
+		"empty file stays empty": {
+			input:    "",
+			opts:     &NormalizeOptions{FinalNewline: true, TrimTrailingSpace: true},
+			expected: "",
+		},
+		"strip bom": {
+			input:    "\ufeffa\n",
+			opts:     &NormalizeOptions{BOM: BOMStrip},
+			expected: "a\n",
+			changes:  []string{NormalizedBOM},
+		},
+		"add bom": {
+			input:    "a\n",
+			opts:     &NormalizeOptions{BOM: BOMAdd},
+			expected: "\ufeffa\n",
+			changes:  []string{NormalizedBOM},
+		},
+		"latin1": {
+			input:    "caf\xe9 \x80\n",
+			opts:     &NormalizeOptions{Encoding: EncodingLatin1},
+			expected: "café \u0080\n",
+			changes:  []string{NormalizedEncoding},
+		},
+		"windows-1252": {
+			input:    "caf\xe9 \x80 \x93q\x94\n",
+			opts:     &NormalizeOptions{Encoding: EncodingWindows1252},
+			expected: "café € “q”\n",
+			changes:  []string{NormalizedEncoding},
+		},
+		"auto keeps utf-8": {
+			input:    "café\n",
+			opts:     &NormalizeOptions{Encoding: EncodingAuto},
+			expected: "café\n",
+		},
+		"auto falls back to windows-1252": {
+			input:    "caf\xe9\n",
+			opts:     &NormalizeOptions{Encoding: EncodingAuto},
+			expected: "café\n",
+			changes:  []string{NormalizedEncoding},
+		},
+		"utf-16le with bom": {
+			input:    "\xff\xfeh\x00\xe9\x00\r\x00\n\x00",
+			opts:     &NormalizeOptions{Encoding: EncodingAuto, BOM: BOMStrip, LineEnding: LineEndingLF},
+			expected: "hé\n",
+			changes:  []string{NormalizedEncoding, NormalizedBOM, NormalizedLineEndings},
+		},
+		"utf-16be with bom": {
+			input:    "\xfe\xff\x00h\x00\xe9",
+			opts:     &NormalizeOptions{Encoding: EncodingUTF16},
+			expected: "\ufeffhé",
+			changes:  []string{NormalizedEncoding},
+		},
+		"utf-16be without bom": {
+			input:    "\x00h\x00i",
+			opts:     &NormalizeOptions{Encoding: EncodingUTF16BE},
+			expected: "hi",
+			changes:  []string{NormalizedEncoding},
+		},
+	}
+
+	for name, tt := range tests {
+		actual, changes, err := NormalizeText([]byte(tt.input), tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if actual != tt.expected {
+			t.Errorf("%s: expected %q, got %q", name, tt.expected, actual)
+		}
+
+		if !reflect.DeepEqual(tt.changes, changes) {
+			t.Errorf("%s: expected changes %v, got %v", name, tt.changes, changes)
+		}
+	}
+
+	for name, opts := range map[string]*NormalizeOptions{
+		"invalid utf-8":    {Encoding: EncodingUTF8},
+		"odd utf-16":       {Encoding: EncodingUTF16LE},
+		"unknown encoding": {Encoding: "ebcdic"},
+	} {
+		if _, _, err := NormalizeText([]byte("caf\xe9!"), opts); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"clean.txt":     "clean\n",
+		"dirty.txt":     "dirty  \r\nfile",
+		"sub/utf16.txt": "\xff\xfeh\x00i\x00",
+		"data.bin":      "bin\x00\x01\x02  \r\n",
+	}
+	for name, content := range files {
+		fileName := filepath.Join(dir, name)
+		if err := MkDir(filepath.Dir(fileName)); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fileName, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := &NormalizeOptions{
+		Encoding:          EncodingAuto,
+		BOM:               BOMStrip,
+		LineEnding:        LineEndingLF,
+		TrimTrailingSpace: true,
+		FinalNewline:      true,
+		CheckOnly:         true,
+	}
+
+	results, err := Normalize(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var changed []string
+	for _, r := range results {
+		rel, _ := filepath.Rel(dir, r.Path)
+		changed = append(changed, filepath.ToSlash(rel))
+	}
+	if !reflect.DeepEqual([]string{"dirty.txt", "sub/utf16.txt"}, changed) {
+		t.Errorf("expected dirty.txt and sub/utf16.txt to need changes, got %v", changed)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(dir, "dirty.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != files["dirty.txt"] {
+		t.Errorf("expected check only to leave files alone, got %q", contents)
+	}
+
+	opts.CheckOnly = false
+	if _, err := Normalize(dir, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"clean.txt":     "clean\n",
+		"dirty.txt":     "dirty\nfile\n",
+		"sub/utf16.txt": "hi\n",
+		"data.bin":      files["data.bin"],
+	}
+	for name, content := range expected {
+		actual, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != content {
+			t.Errorf("%s: expected %q, got %q", name, content, actual)
+		}
+	}
+
+	fi, err := os.Stat(filepath.Join(dir, "dirty.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Errorf("expected mode 0640 to be kept, got %v", fi.Mode().Perm())
+	}
+
+	opts.CheckOnly = true
+	results, err = Normalize(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected no changes on a second run, got %v", results)
+	}
+}
+
+func TestNormalizeWideText(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"utf16le.txt": "\xff\xfeh\x00e\x00l\x00l\x00o\x00 \x00 \x00\n\x00w\x00o\x00r\x00l\x00d\x00",
+		"utf16be.txt": "\xfe\xff\x00h\x00i\x00 \x00\n",
+		"utf32le.txt": "\xff\xfe\x00\x00h\x00\x00\x00 \x00\x00\x00\n\x00\x00\x00",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// without an Encoding the 8 bit rules must not touch wide text
+	opts := &NormalizeOptions{
+		LineEnding:        LineEndingCRLF,
+		TrimTrailingSpace: true,
+		FinalNewline:      true,
+	}
+
+	results, err := Normalize(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected wide text to be skipped, got %v", results)
+	}
+
+	for name, content := range files {
+		actual, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != content {
+			t.Errorf("%s: expected %q, got %q", name, content, actual)
+		}
+	}
+
+	if _, _, err := NormalizeText([]byte(files["utf16le.txt"]), opts); !errors.Is(err, ErrInvalid) {
+		t.Errorf("text: expected %v, got %v", ErrInvalid, err)
+	}
+
+	// utf-32 is skipped even with auto detection
+	opts.Encoding = EncodingAuto
+	results, err = Normalize(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var changed []string
+	for _, r := range results {
+		changed = append(changed, filepath.Base(r.Path))
+	}
+	sort.Strings(changed)
+	if !reflect.DeepEqual([]string{"utf16be.txt", "utf16le.txt"}, changed) {
+		t.Errorf("expected the utf-16 files to change, got %v", changed)
+	}
+
+	actual, err := os.ReadFile(filepath.Join(dir, "utf16le.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != "\ufeffhello\r\nworld\r\n" {
+		t.Errorf("expected decoded text, got %q", actual)
+	}
+	// nor can a UTF-16 Encoding make it read as UTF-16
+	for _, enc := range []TextEncoding{EncodingUTF16, EncodingUTF16LE} {
+		opts.Encoding = enc
+		if _, err := Normalize(filepath.Join(dir, "utf32le.txt"), opts); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := os.ReadFile(filepath.Join(dir, "utf32le.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != files["utf32le.txt"] {
+			t.Errorf("%s: expected %q, got %q", enc, files["utf32le.txt"], actual)
+		}
+
+		if _, _, err := NormalizeText([]byte(files["utf32le.txt"]), opts); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: text: expected %v, got %v", enc, ErrInvalid, err)
+		}
+	}
+}
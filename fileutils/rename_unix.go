@@ -0,0 +1,62 @@
+//go:build !windows
+
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// renameExclusive and linkFile are replaced in tests to reach the fallbacks.
+var (
+	renameExclusive = renameat2
+	linkFile        = os.Link
+)
+
+// renameNoReplace moves src to dst, failing with an error matching
+// fs.ErrExist when dst exists rather than replacing it. Checking first and
+// then renaming would race, so on linux renameat2 does it in one step.
+// Elsewhere files and symlinks are hard linked, which fails when dst exists,
+// and the original removed, and a folder, which cannot be linked, is renamed
+// over an empty folder created exclusively at dst, as rename(2) only replaces
+// folders that are empty.
+func renameNoReplace(src, dst string, dir bool) error {
+	err := renameExclusive(src, dst)
+	if !errors.Is(err, ErrUnsupported) {
+		return err
+	}
+
+	if dir {
+		if err := os.Mkdir(dst, 0700); err != nil {
+			return err
+		}
+
+		// os.Rename refuses to replace any folder, empty or not
+		if err := syscall.Rename(src, dst); err != nil {
+			os.Remove(dst)
+			return &os.LinkError{Op: "rename", Old: src, New: dst, Err: err}
+		}
+
+		return nil
+	}
+
+	err = linkFile(src, dst)
+	if err == nil {
+		return os.Remove(src)
+	}
+
+	// vfat and exfat, where volume trashes often are, have no hard links.
+	// A plain rename after checking is the best left there.
+	if !errors.Is(err, syscall.EPERM) && !errors.Is(err, syscall.ENOTSUP) && !errors.Is(err, syscall.EOPNOTSUPP) {
+		return err
+	}
+
+	if _, err := os.Lstat(dst); err == nil {
+		return &os.LinkError{Op: "rename", Old: src, New: dst, Err: syscall.EEXIST}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return os.Rename(src, dst)
+}
@@ -0,0 +1,94 @@
+//go:build !windows
+
+package fileutils
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestRenameNoReplace(t *testing.T) {
+	tests := map[string]struct {
+		rename func(src, dst string) error
+		link   func(src, dst string) error
+	}{
+		"renameat2": {
+			rename: renameat2,
+			link:   os.Link,
+		},
+		"link": {
+			rename: func(src, dst string) error { return ErrUnsupported },
+			link:   os.Link,
+		},
+		"no hard links": {
+			rename: func(src, dst string) error { return ErrUnsupported },
+			link: func(src, dst string) error {
+				return &os.LinkError{Op: "link", Old: src, New: dst, Err: syscall.EPERM}
+			},
+		},
+	}
+
+	savedRename, savedLink := renameExclusive, linkFile
+	t.Cleanup(func() { renameExclusive, linkFile = savedRename, savedLink })
+
+	for name, tt := range tests {
+		renameExclusive, linkFile = tt.rename, tt.link
+		dir := t.TempDir()
+
+		src := filepath.Join(dir, "src")
+		dst := filepath.Join(dir, "dst")
+		if err := os.WriteFile(src, []byte("moved"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst, []byte("kept"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := renameNoReplace(src, dst, false); !errors.Is(err, fs.ErrExist) {
+			t.Errorf("%s: expected %v, got %v", name, fs.ErrExist, err)
+		}
+		if b, err := os.ReadFile(dst); err != nil || string(b) != "kept" {
+			t.Errorf("%s: expected %q, got %q %v", name, "kept", string(b), err)
+		}
+
+		if err := os.Remove(dst); err != nil {
+			t.Fatal(err)
+		}
+		if err := renameNoReplace(src, dst, false); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if b, err := os.ReadFile(dst); err != nil || string(b) != "moved" {
+			t.Errorf("%s: expected %q, got %q %v", name, "moved", string(b), err)
+		}
+		if _, err := os.Lstat(src); !os.IsNotExist(err) {
+			t.Errorf("%s: expected source to be gone, got %v", name, err)
+		}
+
+		srcDir := filepath.Join(dir, "srcdir")
+		dstDir := filepath.Join(dir, "dstdir")
+		if err := MkDir(filepath.Join(srcDir, "sub")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Mkdir(dstDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := renameNoReplace(srcDir, dstDir, true); !errors.Is(err, fs.ErrExist) {
+			t.Errorf("%s: folder: expected %v, got %v", name, fs.ErrExist, err)
+		}
+
+		if err := os.Remove(dstDir); err != nil {
+			t.Fatal(err)
+		}
+		if err := renameNoReplace(srcDir, dstDir, true); err != nil {
+			t.Fatalf("%s: folder: %v", name, err)
+		}
+		if !FolderExists(filepath.Join(dstDir, "sub")) {
+			t.Errorf("%s: folder: expected contents to move", name)
+		}
+	}
+}
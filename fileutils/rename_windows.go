@@ -0,0 +1,21 @@
+package fileutils
+
+import (
+	"os"
+)
+
+// renameNoReplace moves src to dst, failing with an error matching
+// fs.ErrExist when dst exists rather than replacing it. Files are hard linked
+// and the original removed, folders are renamed, which never replaces an
+// existing folder on windows.
+func renameNoReplace(src, dst string, dir bool) error {
+	if dir {
+		return os.Rename(src, dst)
+	}
+
+	if err := os.Link(src, dst); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
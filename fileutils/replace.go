@@ -0,0 +1,145 @@
+package fileutils
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+type ReplaceOptions struct {
+	// treat the pattern and replacement as literal text, otherwise the
+	// pattern is a regular expression and the replacement may refer to
+	// capture groups as $1 or ${name}
+	Literal    bool
+	IgnoreCase bool
+	// only change files matching one of these Glob patterns, relative to the
+	// root, all files when empty
+	Include []string
+	// skip files larger than MaxFileSize bytes, zero means no limit
+	MaxFileSize int64
+	// work out the changes and their diffs without writing anything
+	DryRun bool
+	// copy each file to fileName+BackupSuffix before changing it
+	BackupSuffix string
+}
+
+type ReplaceResult struct {
+	Path         string
+	Replacements int
+	// unified diff of the change with DryRun, paths are relative to the root
+	Diff string
+}
+
+// Replace replaces every match of pattern with replacement in the text files
+// below root, which may also be a single file, skipping binary files. Each
+// changed file is rewritten atomically with its permissions kept. It returns
+// a result for every file that changed, or would with DryRun, and stops at
+// the first file that cannot be changed.
+func Replace(root, pattern, replacement string, opts *ReplaceOptions) ([]ReplaceResult, error) {
+	var funcName string = "Replace"
+
+	if opts == nil {
+		opts = &ReplaceOptions{}
+	}
+
+	expr := pattern
+	if opts.Literal {
+		expr = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, newTargetError(funcName, root, pattern, "invalid pattern", err)
+	}
+
+	var globs *globMatcher
+	if len(opts.Include) > 0 {
+		globs, err = newGlobMatcher(opts.Include)
+		if err != nil {
+			return nil, newError(funcName, root, "invalid include pattern", err)
+		}
+	}
+
+	var results []ReplaceResult
+
+	err = walkMatching(root, globs, func(fileName, rel string) error {
+		result, err := replaceFile(fileName, rel, re, replacement, opts)
+		if err != nil {
+			return newError(funcName, fileName, "error replacing in file", err)
+		}
+		if result.Replacements > 0 {
+			results = append(results, result)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return results, newError(funcName, root, "error walking target", err)
+	}
+
+	return results, nil
+}
+
+func replaceFile(fileName, rel string, re *regexp.Regexp, replacement string, opts *ReplaceOptions) (ReplaceResult, error) {
+	result := ReplaceResult{Path: fileName}
+
+	fi, err := os.Stat(fileName)
+	if err != nil {
+		return result, err
+	}
+
+	if opts.MaxFileSize > 0 && fi.Size() > opts.MaxFileSize {
+		return result, nil
+	}
+
+	b, err := os.ReadFile(filepath.Clean(fileName))
+	if err != nil {
+		return result, err
+	}
+
+	if detectType(sniffHead(b)).Binary {
+		return result, nil
+	}
+
+	before := string(b)
+
+	matches := re.FindAllStringIndex(before, -1)
+	if len(matches) == 0 {
+		return result, nil
+	}
+
+	var after string
+	if opts.Literal {
+		after = re.ReplaceAllLiteralString(before, replacement)
+	} else {
+		after = re.ReplaceAllString(before, replacement)
+	}
+
+	if after == before {
+		return result, nil
+	}
+
+	result.Replacements = len(matches)
+
+	if opts.DryRun {
+		result.Diff = unifiedDiff("a/"+rel, "b/"+rel, before, after)
+		return result, nil
+	}
+
+	if opts.BackupSuffix != "" {
+		if _, err := CopyFile(fileName, fileName+opts.BackupSuffix, nil); err != nil {
+			return result, err
+		}
+	}
+
+	err = writeFileAtomic(fileName, fi.Mode().Perm(), func(w io.Writer) error {
+		_, err := io.WriteString(w, after)
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,134 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReplace(t *testing.T) {
+	dir := testTree(t, fstest.MapFS{
+		"go.mod":          {Data: []byte("module example\n\ngo 1.18\n"), Mode: 0640},
+		"sub/version.txt": {Data: []byte("version: 1.2.3\nother: 1.2.3\n"), Mode: 0640},
+		"sub/none.txt":    {Data: []byte("nothing\n"), Mode: 0640},
+		"bin/data.bin":    {Data: []byte("version: 1.2.3\x00\x01\x02"), Mode: 0640},
+		"big/large.txt":   {Data: []byte(strings.Repeat("version: 1.2.3\n", 100)), Mode: 0640},
+	})
+
+	results, err := Replace(dir, `(\d+)\.(\d+)\.(\d+)`, "$1.$2.9", &ReplaceOptions{DryRun: true, MaxFileSize: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("expected 1 changed file, got %v", results)
+	}
+
+	r := results[0]
+	if r.Path != filepath.Join(dir, "sub", "version.txt") || r.Replacements != 2 {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	expected := "--- a/sub/version.txt\n+++ b/sub/version.txt\n" +
+		"@@ -1,2 +1,2 @@\n-version: 1.2.3\n-other: 1.2.3\n+version: 1.2.9\n+other: 1.2.9\n"
+	if r.Diff != expected {
+		t.Errorf("expected diff\n%v\ngot\n%v", expected, r.Diff)
+	}
+
+	contents, err := os.ReadFile(r.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "version: 1.2.3\nother: 1.2.3\n" {
+		t.Errorf("expected dry run to leave the file alone, got %q", contents)
+	}
+
+	results, err = Replace(dir, "1.2.3", "2.0.0", &ReplaceOptions{Literal: true, BackupSuffix: ".bak", Include: []string{"**/*.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 {
+		t.Errorf("expected 2 changed files, got %d", len(results))
+	}
+
+	contents, err = os.ReadFile(filepath.Join(dir, "sub", "version.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "version: 2.0.0\nother: 2.0.0\n" {
+		t.Errorf("unexpected contents %q", contents)
+	}
+
+	backup, err := os.ReadFile(filepath.Join(dir, "sub", "version.txt.bak"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(backup) != "version: 1.2.3\nother: 1.2.3\n" {
+		t.Errorf("unexpected backup %q", backup)
+	}
+
+	fi, err := os.Stat(filepath.Join(dir, "sub", "version.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Errorf("expected mode 0640 to be kept, got %v", fi.Mode().Perm())
+	}
+
+	bin, err := os.ReadFile(filepath.Join(dir, "bin", "data.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(bin), "1.2.3") {
+		t.Errorf("expected binary file to be skipped")
+	}
+}
+
+func TestReplaceFile(t *testing.T) {
+	dir := testTree(t, fstest.MapFS{
+		"go.mod": {Data: []byte("module example\n\ngo 1.18\n")},
+	})
+	fileName := filepath.Join(dir, "go.mod")
+
+	results, err := Replace(fileName, `(?m)^go (\S+)$`, "go 1.21", &ReplaceOptions{IgnoreCase: true, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || !strings.HasPrefix(results[0].Diff, "--- a/go.mod\n") {
+		t.Errorf("expected diff relative to the folder, got %v", results)
+	}
+
+	results, err = Replace(fileName, `(?m)^go (\S+)$`, "go 1.21", &ReplaceOptions{IgnoreCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || results[0].Replacements != 1 {
+		t.Fatalf("expected one replacement, got %v", results)
+	}
+
+	if results[0].Diff != "" {
+		t.Errorf("expected no diff without DryRun, got %v", results[0].Diff)
+	}
+
+	contents, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "module example\n\ngo 1.21\n" {
+		t.Errorf("unexpected contents %q", contents)
+	}
+
+	if _, err := Replace(dir, "(", "", nil); err == nil {
+		t.Errorf("expected error for a bad pattern, got nil")
+	}
+
+	if _, err := Replace(filepath.Join(dir, "nofile"), "x", "y", nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
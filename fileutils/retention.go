@@ -0,0 +1,215 @@
+package fileutils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+type RetentionReason string
+
+const (
+	RetentionReasonMaxAge       RetentionReason = "max_age"
+	RetentionReasonKeepNewest   RetentionReason = "keep_newest"
+	RetentionReasonMaxTotalSize RetentionReason = "max_total_size"
+)
+
+// RetentionPolicy selects files in a folder and decides which of them to
+// remove. Rules combine, a file is removed when any of them applies. Zero
+// values disable a rule.
+type RetentionPolicy struct {
+	// only consider files whose base name matches one of Patterns, using
+	// filepath.Match, or that have one of Extensions, e.g. ".log". All regular
+	// files are considered when both are empty
+	Patterns   []string
+	Extensions []string
+	// descend into subfolders
+	Recursive bool
+	// remove files last modified more than MaxAge ago
+	MaxAge time.Duration
+	// keep only the KeepNewest most recently modified files
+	KeepNewest int
+	// remove the oldest files until the rest add up to MaxTotalSize bytes
+	MaxTotalSize int64
+}
+
+type RetentionOptions struct {
+	// report what would be removed without touching anything
+	DryRun bool
+	// move files into TrashDir, keeping their path relative to the folder,
+	// instead of deleting them
+	TrashDir string
+}
+
+type RetainedFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Reason  RetentionReason
+}
+
+type RetentionReport struct {
+	// files removed, or that would be with DryRun, oldest first
+	Removed      []RetainedFile
+	BytesRemoved int64
+	Kept         int
+	BytesKept    int64
+	// problems with individual files or subfolders, which are skipped
+	Errors []error
+}
+
+// ApplyRetention applies policy to the files in folderPath. Failures with
+// single files do not stop the run, they are collected in the report, the
+// returned error is only set when the folder itself cannot be read.
+func ApplyRetention(folderPath string, policy RetentionPolicy, opts *RetentionOptions) (RetentionReport, error) {
+	var funcName string = "ApplyRetention"
+
+	var report RetentionReport
+
+	if opts == nil {
+		opts = &RetentionOptions{}
+	}
+
+	if !FolderExists(folderPath) {
+		return report, newError(funcName, folderPath, "", ErrNotExist)
+	}
+
+	if !IsFolder(folderPath) {
+		return report, newError(funcName, folderPath, "", ErrNotFolder)
+	}
+
+	for _, pattern := range policy.Patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return report, newTargetError(funcName, folderPath, pattern, "invalid pattern", err)
+		}
+	}
+
+	var files []RetainedFile
+
+	err := filepath.WalkDir(folderPath, func(s string, d fs.DirEntry, e error) error {
+		if e != nil {
+			if s == folderPath {
+				return e
+			}
+			report.Errors = append(report.Errors, newError(funcName, s, "error reading target", e))
+			return nil
+		}
+
+		if d.IsDir() {
+			if s != folderPath && !policy.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() || !policy.matches(d.Name()) {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			report.Errors = append(report.Errors, newError(funcName, s, "error getting file info", err))
+			return nil
+		}
+
+		files = append(files, RetainedFile{Path: s, Size: fi.Size(), ModTime: fi.ModTime()})
+
+		return nil
+	})
+	if err != nil {
+		return report, newError(funcName, folderPath, "error walking target", err)
+	}
+
+	// newest first, so the files to keep come before the ones to remove
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].ModTime.After(files[j].ModTime)
+	})
+
+	now := time.Now()
+	var overSize bool
+
+	for i := range files {
+		f := &files[i]
+
+		switch {
+		case policy.MaxAge > 0 && now.Sub(f.ModTime) > policy.MaxAge:
+			f.Reason = RetentionReasonMaxAge
+		case policy.KeepNewest > 0 && report.Kept >= policy.KeepNewest:
+			f.Reason = RetentionReasonKeepNewest
+		case policy.MaxTotalSize > 0 && (overSize || report.BytesKept+f.Size > policy.MaxTotalSize):
+			overSize = true
+			f.Reason = RetentionReasonMaxTotalSize
+		default:
+			report.Kept++
+			report.BytesKept += f.Size
+		}
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		if f.Reason == "" {
+			continue
+		}
+
+		if !opts.DryRun {
+			if err := removeRetained(folderPath, f.Path, opts.TrashDir); err != nil {
+				report.Errors = append(report.Errors, newError(funcName, f.Path, "error removing file", err))
+				report.Kept++
+				report.BytesKept += f.Size
+				continue
+			}
+		}
+
+		report.Removed = append(report.Removed, f)
+		report.BytesRemoved += f.Size
+	}
+
+	return report, nil
+}
+
+func (p RetentionPolicy) matches(name string) bool {
+	if len(p.Patterns) == 0 && len(p.Extensions) == 0 {
+		return true
+	}
+
+	for _, pattern := range p.Patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	ext := filepath.Ext(name)
+	for _, e := range p.Extensions {
+		if e == ext {
+			return true
+		}
+	}
+
+	return false
+}
+
+func removeRetained(folderPath, fileName, trashDir string) error {
+	if trashDir == "" {
+		return os.Remove(fileName)
+	}
+
+	rel, err := filepath.Rel(folderPath, fileName)
+	if err != nil {
+		return err
+	}
+
+	dst := filepath.Join(trashDir, rel)
+	if err := MkDir(filepath.Dir(dst)); err != nil {
+		return err
+	}
+
+	// never overwrite an earlier run's copy
+	for n := 1; FileExists(dst); n++ {
+		dst = fmt.Sprintf("%v.%d", filepath.Join(trashDir, rel), n)
+	}
+
+	return MoveFile(fileName, dst, nil)
+}
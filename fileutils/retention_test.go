@@ -0,0 +1,178 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Now()
+
+	// name, size, age in hours
+	files := []struct {
+		name string
+		size int
+		age  int
+	}{
+		{"a.log", 10, 1},
+		{"b.log", 10, 2},
+		{"c.log", 10, 3},
+		{"d.log", 10, 48},
+		{"e.txt", 10, 72},
+		{"sub/f.log", 10, 4},
+	}
+
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		for _, f := range files {
+			fileName := filepath.Join(dir, f.name)
+			if err := MkDir(filepath.Dir(fileName)); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(fileName, []byte(strings.Repeat("x", f.size)), 0600); err != nil {
+				t.Fatal(err)
+			}
+			mtime := now.Add(-time.Duration(f.age) * time.Hour)
+			if err := os.Chtimes(fileName, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+
+	tests := map[string]struct {
+		policy   RetentionPolicy
+		expected []string
+		reasons  []RetentionReason
+	}{
+		"max age": {
+			policy:   RetentionPolicy{MaxAge: 24 * time.Hour},
+			expected: []string{"e.txt", "d.log"},
+			reasons:  []RetentionReason{RetentionReasonMaxAge, RetentionReasonMaxAge},
+		},
+		"max age by extension": {
+			policy:   RetentionPolicy{MaxAge: 24 * time.Hour, Extensions: []string{".log"}},
+			expected: []string{"d.log"},
+		},
+		"keep newest by glob": {
+			policy:   RetentionPolicy{KeepNewest: 2, Patterns: []string{"*.log"}, Recursive: true},
+			expected: []string{"d.log", "sub/f.log", "c.log"},
+			reasons:  []RetentionReason{RetentionReasonKeepNewest, RetentionReasonKeepNewest, RetentionReasonKeepNewest},
+		},
+		"max total size": {
+			policy:   RetentionPolicy{MaxTotalSize: 25},
+			expected: []string{"e.txt", "d.log", "c.log"},
+			reasons:  []RetentionReason{RetentionReasonMaxTotalSize, RetentionReasonMaxTotalSize, RetentionReasonMaxTotalSize},
+		},
+		"combined": {
+			policy:   RetentionPolicy{MaxAge: 24 * time.Hour, KeepNewest: 2},
+			expected: []string{"e.txt", "d.log", "c.log"},
+			reasons:  []RetentionReason{RetentionReasonMaxAge, RetentionReasonMaxAge, RetentionReasonKeepNewest},
+		},
+		"nothing matches": {
+			policy: RetentionPolicy{MaxAge: time.Hour, Patterns: []string{"*.gz"}},
+		},
+	}
+
+	for name, tt := range tests {
+		dir := setup(t)
+
+		report, err := ApplyRetention(dir, tt.policy, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(report.Errors) != 0 {
+			t.Errorf("%s: unexpected errors %v", name, report.Errors)
+		}
+
+		var actual []string
+		for i, f := range report.Removed {
+			rel, _ := filepath.Rel(dir, f.Path)
+			actual = append(actual, filepath.ToSlash(rel))
+			if FileExists(f.Path) {
+				t.Errorf("%s: expected %v to be removed", name, rel)
+			}
+			if tt.reasons != nil && i < len(tt.reasons) && f.Reason != tt.reasons[i] {
+				t.Errorf("%s: expected reason %v for %v, got %v", name, tt.reasons[i], rel, f.Reason)
+			}
+		}
+
+		if strings.Join(tt.expected, ",") != strings.Join(actual, ",") {
+			t.Errorf("%s: expected %v removed, got %v", name, tt.expected, actual)
+		}
+
+		if report.BytesRemoved != int64(10*len(tt.expected)) {
+			t.Errorf("%s: expected %d bytes removed, got %d", name, 10*len(tt.expected), report.BytesRemoved)
+		}
+	}
+}
+
+func TestApplyRetentionOptions(t *testing.T) {
+	dir := t.TempDir()
+	trash := filepath.Join(t.TempDir(), "trash")
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"old.log", "sub/old.log"} {
+		fileName := filepath.Join(dir, name)
+		if err := MkDir(filepath.Dir(fileName)); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fileName, []byte("content"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(fileName, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	policy := RetentionPolicy{MaxAge: time.Hour, Recursive: true}
+
+	report, err := ApplyRetention(dir, policy, &RetentionOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Removed) != 2 {
+		t.Errorf("expected 2 files reported, got %d", len(report.Removed))
+	}
+	for _, f := range report.Removed {
+		if !FileExists(f.Path) {
+			t.Errorf("expected dry run to keep %v", f.Path)
+		}
+	}
+
+	// the second run finds the first run's copy in the trash
+	for i := 0; i < 2; i++ {
+		if err := os.WriteFile(filepath.Join(dir, "old.log"), []byte("content"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filepath.Join(dir, "old.log"), old, old); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err = ApplyRetention(dir, policy, &RetentionOptions{TrashDir: trash})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Errors) != 0 {
+			t.Errorf("unexpected errors %v", report.Errors)
+		}
+	}
+
+	for _, name := range []string{"old.log", "old.log.1", "sub/old.log"} {
+		if !FileExists(filepath.Join(trash, name)) {
+			t.Errorf("expected %v in trash", name)
+		}
+	}
+
+	if FileExists(filepath.Join(dir, "old.log")) {
+		t.Errorf("expected old.log to be moved")
+	}
+
+	_, err = ApplyRetention(filepath.Join(dir, "nofolder"), policy, nil)
+	if err == nil {
+		t.Errorf("expected error for a missing folder, got nil")
+	}
+}
@@ -0,0 +1,386 @@
+package fileutils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"unsafe"
+)
+
+const atRemoveDir = 0x200
+
+// Root confines file operations to a folder. Paths are resolved one component
+// at a time relative to open directory descriptors, with symlinks and ".."
+// interpreted as if the folder were the filesystem root, so nothing can be
+// reached outside it even when the tree changes during resolution.
+type Root struct {
+	name string
+	fd   int
+}
+
+func OpenRoot(folderPath string) (*Root, error) {
+	var funcName string = "OpenRoot"
+
+	fd, err := syscall.Open(folderPath, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, newError(funcName, folderPath, "error opening folder", err)
+	}
+
+	return &Root{name: filepath.Clean(folderPath), fd: fd}, nil
+}
+
+func (r *Root) Name() string {
+	return r.name
+}
+
+func (r *Root) Close() error {
+	return syscall.Close(r.fd)
+}
+
+// Join is SecureJoin with the root's folder.
+func (r *Root) Join(unsafePath string) (string, error) {
+	return SecureJoin(r.name, unsafePath)
+}
+
+func (r *Root) Open(fileName string) (*os.File, error) {
+	return r.OpenFile(fileName, os.O_RDONLY, 0)
+}
+
+func (r *Root) OpenFile(fileName string, flag int, perm os.FileMode) (*os.File, error) {
+	var funcName string = "Root.OpenFile"
+
+	f, err := r.openFile(fileName, flag, perm)
+	if err != nil {
+		return nil, newError(funcName, fileName, "error opening file", err)
+	}
+
+	return f, nil
+}
+
+func (r *Root) GetFile(fileName string) (*os.File, error) {
+	var funcName string = "Root.GetFile"
+
+	f, err := r.openFile(fileName, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0655)
+	if err != nil {
+		return nil, newError(funcName, fileName, "error opening file", err)
+	}
+
+	return f, nil
+}
+
+func (r *Root) MkDir(dir string) error {
+	var funcName string = "Root.MkDir"
+
+	parts := splitPath(dir)
+	for i := range parts {
+		p := filepath.Join(parts[:i+1]...)
+
+		dirfd, base, err := r.walk(p, true)
+		if err != nil {
+			return newError(funcName, dir, "error resolving path", err)
+		}
+
+		if base != "" {
+			err = syscall.Mkdirat(dirfd, base, uint32(os.ModePerm))
+			if errors.Is(err, syscall.EEXIST) {
+				// fine as long as it is a folder
+				var fd int
+				fd, err = syscall.Openat(dirfd, base, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+				if err == nil {
+					syscall.Close(fd)
+				}
+			}
+		}
+		syscall.Close(dirfd)
+
+		if err != nil {
+			return newError(funcName, dir, "error creating folder", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Root) MkFile(fileName string) error {
+	var funcName string = "Root.MkFile"
+
+	f, err := r.openFile(fileName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if errors.Is(err, syscall.EEXIST) {
+		return newError(funcName, fileName, "", ErrExist)
+	}
+	if err != nil {
+		return newError(funcName, fileName, "error creating file", err)
+	}
+	f.Close()
+
+	return nil
+}
+
+func (r *Root) WriteFile(fileName string, fileContent string) error {
+	var funcName string = "Root.WriteFile"
+
+	f, err := r.GetFile(fileName)
+	if err != nil {
+		return newError(funcName, fileName, "error preparing to write file", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write([]byte(fileContent))
+	if err != nil {
+		return newError(funcName, fileName, "error writing file", err)
+	}
+
+	return nil
+}
+
+func (r *Root) ReadFile(fileName string) ([]byte, error) {
+	var funcName string = "Root.ReadFile"
+
+	f, err := r.openFile(fileName, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, newError(funcName, fileName, "error opening file", err)
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, newError(funcName, fileName, "error reading file", err)
+	}
+
+	return b, nil
+}
+
+// Remove removes a file, symlink or empty folder. A symlink is removed
+// itself, not its target.
+func (r *Root) Remove(fileName string) error {
+	var funcName string = "Root.Remove"
+
+	dirfd, base, err := r.walk(fileName, false)
+	if err != nil {
+		return newError(funcName, fileName, "error resolving path", err)
+	}
+	defer syscall.Close(dirfd)
+
+	if base == "" {
+		return newError(funcName, fileName, "refusing to remove root", ErrInvalid)
+	}
+
+	err = syscall.Unlinkat(dirfd, base)
+	if errors.Is(err, syscall.EISDIR) || errors.Is(err, syscall.EPERM) {
+		err = unlinkat(dirfd, base, atRemoveDir)
+	}
+	if err != nil {
+		return newError(funcName, fileName, "error removing", err)
+	}
+
+	return nil
+}
+
+// Find is fileutils.Find inside the root. Returned paths are relative to the
+// root and symlinked folders are not descended into.
+func (r *Root) Find(folderPath, ext string) ([]string, error) {
+	var funcName string = "Root.Find"
+
+	f, err := r.openFile(folderPath, os.O_RDONLY|syscall.O_DIRECTORY, 0)
+	if err != nil {
+		return []string{}, newError(funcName, folderPath, "error opening folder", err)
+	}
+
+	var files []string
+	err = findAt(f, filepath.Clean(folderPath), ext, &files)
+	if err != nil {
+		return []string{}, newError(funcName, folderPath, "error walking target", err)
+	}
+
+	return files, nil
+}
+
+func findAt(dir *os.File, dirName, ext string, files *[]string) error {
+	defer dir.Close()
+
+	entries, err := dir.ReadDir(-1)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	for _, e := range entries {
+		name := filepath.Join(dirName, e.Name())
+
+		if filepath.Ext(e.Name()) == ext {
+			*files = append(*files, name)
+		}
+
+		if !e.IsDir() {
+			continue
+		}
+
+		fd, err := syscall.Openat(int(dir.Fd()), e.Name(), syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			return err
+		}
+
+		if err := findAt(os.NewFile(uintptr(fd), name), name, ext, files); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Root) openFile(fileName string, flag int, perm os.FileMode) (*os.File, error) {
+	dirfd, base, err := r.walk(fileName, true)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(dirfd)
+
+	if base == "" {
+		base = "."
+	}
+
+	fd, err := syscall.Openat(dirfd, base, flag|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, uint32(perm.Perm()))
+	if err != nil {
+		return nil, err
+	}
+
+	return os.NewFile(uintptr(fd), filepath.Join(r.name, fileName)), nil
+}
+
+// walk resolves fileName inside the root and returns a descriptor for the
+// folder holding its final component, which the caller must close, and the
+// name of that component. The name is empty when fileName resolves to a
+// folder that has been fully opened. When followLast is set a final symlink
+// is resolved too. A missing final component is fine, it may be about to be
+// created.
+func (r *Root) walk(fileName string, followLast bool) (int, string, error) {
+	root, err := syscall.Dup(r.fd)
+	if err != nil {
+		return -1, "", err
+	}
+
+	stack := []int{root}
+	closeAll := func() {
+		for _, fd := range stack {
+			syscall.Close(fd)
+		}
+	}
+
+	var hops int
+	parts := splitPath(fileName)
+
+	for len(parts) > 0 {
+		comp := parts[0]
+		parts = parts[1:]
+		last := len(parts) == 0
+		top := stack[len(stack)-1]
+
+		if comp == ".." {
+			if len(stack) > 1 {
+				syscall.Close(top)
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+
+		if last && !followLast {
+			return popTop(stack, comp)
+		}
+
+		var dest string
+		if last {
+			// a missing or non symlink final component is returned as is
+			dest, err = readlinkat(top, comp)
+			if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOENT) {
+				return popTop(stack, comp)
+			}
+			if err != nil {
+				closeAll()
+				return -1, "", err
+			}
+		} else {
+			fd, err := syscall.Openat(top, comp, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+			if err == nil {
+				stack = append(stack, fd)
+				continue
+			}
+			if !errors.Is(err, syscall.ELOOP) && !errors.Is(err, syscall.ENOTDIR) {
+				closeAll()
+				return -1, "", err
+			}
+
+			// either a symlink or not a folder at all
+			dest, err = readlinkat(top, comp)
+			if errors.Is(err, syscall.EINVAL) {
+				closeAll()
+				return -1, "", syscall.ENOTDIR
+			}
+			if err != nil {
+				closeAll()
+				return -1, "", err
+			}
+		}
+
+		hops++
+		if hops > maxSymlinkHops {
+			closeAll()
+			return -1, "", syscall.ELOOP
+		}
+
+		if filepath.IsAbs(dest) {
+			for _, fd := range stack[1:] {
+				syscall.Close(fd)
+			}
+			stack = stack[:1]
+		}
+		parts = append(splitPath(dest), parts...)
+	}
+
+	return popTop(stack, "")
+}
+
+// popTop closes everything but the top of stack and returns it with name.
+func popTop(stack []int, name string) (int, string, error) {
+	for _, fd := range stack[:len(stack)-1] {
+		syscall.Close(fd)
+	}
+
+	return stack[len(stack)-1], name, nil
+}
+
+func readlinkat(dirfd int, name string) (string, error) {
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return "", err
+	}
+
+	for size := 256; ; size *= 2 {
+		buf := make([]byte, size)
+		n, _, errno := syscall.Syscall6(syscall.SYS_READLINKAT, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&buf[0])), uintptr(size), 0, 0)
+		if errno != 0 {
+			return "", errno
+		}
+		if int(n) < size {
+			return string(buf[:n]), nil
+		}
+	}
+}
+
+func unlinkat(dirfd int, name string, flags int) error {
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_UNLINKAT, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
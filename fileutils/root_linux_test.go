@@ -0,0 +1,184 @@
+package fileutils
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRootConfinement(t *testing.T) {
+	dir := testTree(t, fstest.MapFS{
+		"secret.txt":           {Data: []byte("secret")},
+		"root/data/sub":        {Mode: fs.ModeDir},
+		"root/data/escape":     {Data: []byte("../../secret.txt"), Mode: fs.ModeSymlink},
+		"root/data/up":         {Data: []byte("../.."), Mode: fs.ModeSymlink},
+		"root/data/sub/parent": {Data: []byte(".."), Mode: fs.ModeSymlink},
+	})
+
+	err := os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(dir, "root", "data", "abs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := OpenRoot(filepath.Join(dir, "root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	tests := map[string]string{
+		"dot dot":           "../secret.txt",
+		"nested dot dot":    "data/../../secret.txt",
+		"relative symlink":  "data/escape",
+		"absolute symlink":  "data/abs",
+		"symlinked folder":  "data/up/secret.txt",
+		"parent via link":   "data/sub/parent/../../secret.txt",
+		"absolute path arg": filepath.Join(dir, "secret.txt"),
+	}
+
+	for name, p := range tests {
+		b, err := r.ReadFile(p)
+		if err == nil {
+			t.Errorf("%s: expected error, read %q", name, b)
+		}
+	}
+
+	// writes either fail or land inside the root
+	for _, p := range tests {
+		_ = r.WriteFile(p, "overwritten")
+	}
+
+	contents, err := os.ReadFile(filepath.Join(dir, "secret.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(contents) != "secret" {
+		t.Errorf("expected file outside root to be untouched, got %q", contents)
+	}
+
+	if !FileExists(filepath.Join(r.Name(), "secret.txt")) {
+		t.Errorf("expected escaping writes to land inside the root")
+	}
+}
+
+func TestRootOperations(t *testing.T) {
+	dir := testTree(t, fstest.MapFS{
+		"secret.txt":           {Data: []byte("secret")},
+		"root/data/sub":        {Mode: fs.ModeDir},
+		"root/data/escape":     {Data: []byte("../../secret.txt"), Mode: fs.ModeSymlink},
+		"root/data/up":         {Data: []byte("../.."), Mode: fs.ModeSymlink},
+		"root/data/sub/parent": {Data: []byte(".."), Mode: fs.ModeSymlink},
+	})
+
+	r, err := OpenRoot(filepath.Join(dir, "root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	err = r.MkDir("data/new/deeper")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.MkDir("data/new/deeper")
+	if err != nil {
+		t.Errorf("expected MkDir on existing folder to succeed, got %v", err)
+	}
+
+	err = r.MkFile("data/new/deeper/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.MkFile("data/new/deeper/file.txt")
+	if err == nil {
+		t.Errorf("expected error creating existing file, got nil")
+	}
+
+	err = r.MkDir("data/new/deeper/file.txt")
+	if err == nil {
+		t.Errorf("expected error creating folder over file, got nil")
+	}
+
+	err = r.WriteFile("data/sub/parent/sub/notes.txt", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(r.Name(), "data", "sub", "notes.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(contents) != "hello" {
+		t.Errorf("expected hello, got %q", contents)
+	}
+
+	// the link itself is removed, not its target
+	err = r.Remove("data/sub/parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsFolder(filepath.Join(r.Name(), "data")) {
+		t.Errorf("expected link target to survive removal")
+	}
+
+	err = r.Remove("data/new/deeper/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Remove("data/new/deeper")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Remove("..")
+	if err == nil {
+		t.Errorf("expected error removing root, got nil")
+	}
+}
+
+func TestRootFind(t *testing.T) {
+	dir := testTree(t, fstest.MapFS{
+		"secret.txt":           {Data: []byte("secret")},
+		"root/data/sub":        {Mode: fs.ModeDir},
+		"root/data/escape":     {Data: []byte("../../secret.txt"), Mode: fs.ModeSymlink},
+		"root/data/up":         {Data: []byte("../.."), Mode: fs.ModeSymlink},
+		"root/data/sub/parent": {Data: []byte(".."), Mode: fs.ModeSymlink},
+	})
+
+	r, err := OpenRoot(filepath.Join(dir, "root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for _, f := range []string{"data/a.txt", "data/sub/b.txt", "data/sub/c.csv"} {
+		if err := r.MkFile(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actual, err := r.Find("data", ".txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"data/a.txt", "data/sub/b.txt"}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	_, err = r.Find("data/up", ".txt")
+	if err != nil {
+		t.Errorf("expected symlinked folder inside root to resolve, got %v", err)
+	}
+}
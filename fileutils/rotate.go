@@ -0,0 +1,384 @@
+package fileutils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const rotateTimeFormat = "20060102T150405.000"
+
+type RotateOptions struct {
+	// rotate before a write would take the file past MaxSize bytes
+	MaxSize int64
+	// rotate on the first write after each interval boundary
+	Interval time.Duration
+	// gzip rotated files in the background
+	Compress bool
+	// keep at most MaxFiles rotated files
+	MaxFiles int
+	// remove rotated files older than MaxAge
+	MaxAge time.Duration
+	// close and reopen the file on SIGHUP, for use with external rotation
+	ReopenOnSIGHUP bool
+}
+
+// RotatingFile is an io.WriteCloser that rotates fileName by size and/or
+// time. Rotated files are named fileName.<timestamp>, with a .gz suffix when
+// compressed. It is safe for concurrent use.
+type RotatingFile struct {
+	fileName string
+	opts     RotateOptions
+	now      func() time.Time
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	next   time.Time
+	closed bool
+
+	bg     sync.WaitGroup
+	bgMu   sync.Mutex
+	sighup chan os.Signal
+	done   chan struct{}
+}
+
+func NewRotatingFile(fileName string, opts *RotateOptions) (*RotatingFile, error) {
+	var funcName string = "NewRotatingFile"
+
+	r := &RotatingFile{
+		fileName: fileName,
+		now:      time.Now,
+		done:     make(chan struct{}),
+	}
+	if opts != nil {
+		r.opts = *opts
+	}
+
+	if err := r.open(); err != nil {
+		return nil, newError(funcName, fileName, "error opening file", err)
+	}
+
+	if r.opts.ReopenOnSIGHUP {
+		r.sighup = make(chan os.Signal, 1)
+		signal.Notify(r.sighup, syscall.SIGHUP)
+		go r.handleSIGHUP()
+	}
+
+	return r, nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	var funcName string = "RotatingFile.Write"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, newError(funcName, r.fileName, "", ErrClosed)
+	}
+
+	if r.shouldRotate(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, newError(funcName, r.fileName, "error rotating file", err)
+		}
+	}
+
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	if err != nil {
+		return n, newError(funcName, r.fileName, "error writing file", err)
+	}
+
+	return n, nil
+}
+
+// Rotate rotates the file now, regardless of size or interval.
+func (r *RotatingFile) Rotate() error {
+	var funcName string = "RotatingFile.Rotate"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return newError(funcName, r.fileName, "", ErrClosed)
+	}
+
+	if err := r.rotate(); err != nil {
+		return newError(funcName, r.fileName, "error rotating file", err)
+	}
+
+	return nil
+}
+
+// Reopen opens fileName again, picking up a new file when something else has
+// moved the current one away. The current file is only closed once the new
+// one is open, so a failed reopen carries on writing to it.
+func (r *RotatingFile) Reopen() error {
+	var funcName string = "RotatingFile.Reopen"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return newError(funcName, r.fileName, "", ErrClosed)
+	}
+
+	if err := r.open(); err != nil {
+		return newError(funcName, r.fileName, "error reopening file", err)
+	}
+
+	return nil
+}
+
+// Close closes the file and waits for any background compression and
+// cleanup to finish.
+func (r *RotatingFile) Close() error {
+	var funcName string = "RotatingFile.Close"
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	if r.sighup != nil {
+		signal.Stop(r.sighup)
+	}
+
+	err := r.f.Close()
+	r.mu.Unlock()
+
+	r.bg.Wait()
+
+	if err != nil {
+		return newError(funcName, r.fileName, "error closing file", err)
+	}
+
+	return nil
+}
+
+// open opens fileName and swaps it in for the current file, which is closed
+// afterwards. On failure the current file is left in place.
+func (r *RotatingFile) open() error {
+	f, size, err := r.openFile()
+	if err != nil {
+		return err
+	}
+
+	return r.use(f, size)
+}
+
+func (r *RotatingFile) openFile() (*os.File, int64, error) {
+	f, err := GetFile(r.fileName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+
+	return f, fi.Size(), nil
+}
+
+// use makes f, already size bytes long, the file being written and closes
+// the previous one.
+func (r *RotatingFile) use(f *os.File, size int64) error {
+	old := r.f
+
+	r.f = f
+	r.size = size
+	if r.opts.Interval > 0 {
+		r.next = r.now().Truncate(r.opts.Interval).Add(r.opts.Interval)
+	}
+
+	if old != nil {
+		return old.Close()
+	}
+
+	return nil
+}
+
+func (r *RotatingFile) shouldRotate(n int64) bool {
+	if r.size == 0 {
+		// nothing to rotate, just move on to the current interval
+		if r.opts.Interval > 0 && !r.now().Before(r.next) {
+			r.next = r.now().Truncate(r.opts.Interval).Add(r.opts.Interval)
+		}
+		return false
+	}
+
+	if r.opts.MaxSize > 0 && r.size+n > r.opts.MaxSize {
+		return true
+	}
+
+	return r.opts.Interval > 0 && !r.now().Before(r.next)
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.f.Sync(); err != nil {
+		return err
+	}
+
+	// the file is renamed while still open, so that whatever goes wrong
+	// there is a file to carry on writing to
+	rotated := r.rotatedName()
+	if err := os.Rename(r.fileName, rotated); err != nil {
+		return err
+	}
+
+	f, size, err := r.openFile()
+	if err != nil {
+		// best effort, put the current file back where it belongs
+		_ = os.Rename(rotated, r.fileName)
+		return err
+	}
+
+	// the old file was synced above, failing to close it loses nothing
+	_ = r.use(f, size)
+
+	if r.opts.Compress || r.opts.MaxFiles > 0 || r.opts.MaxAge > 0 {
+		r.bg.Add(1)
+		go func() {
+			defer r.bg.Done()
+			r.bgMu.Lock()
+			defer r.bgMu.Unlock()
+
+			if r.opts.Compress {
+				// best effort, an uncompressed file is still kept
+				_ = gzipFile(rotated)
+			}
+			r.prune()
+		}()
+	}
+
+	return nil
+}
+
+func (r *RotatingFile) rotatedName() string {
+	base := fmt.Sprintf("%v.%v", r.fileName, r.now().Format(rotateTimeFormat))
+
+	name := base
+	for i := 1; FileExists(name) || FileExists(name+".gz"); i++ {
+		name = fmt.Sprintf("%v-%d", base, i)
+	}
+
+	return name
+}
+
+// rotatedFiles lists rotated files, newest first.
+func (r *RotatingFile) rotatedFiles() ([]string, error) {
+	dir := filepath.Dir(r.fileName)
+	prefix := filepath.Base(r.fileName) + "."
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || len(name) < len(prefix)+len(rotateTimeFormat) {
+			continue
+		}
+		if strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		if _, err := time.Parse(rotateTimeFormat, name[len(prefix):len(prefix)+len(rotateTimeFormat)]); err != nil {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return strings.TrimSuffix(files[i], ".gz") > strings.TrimSuffix(files[j], ".gz")
+	})
+
+	return files, nil
+}
+
+func (r *RotatingFile) prune() {
+	files, err := r.rotatedFiles()
+	if err != nil {
+		return
+	}
+
+	cutoff := r.now().Add(-r.opts.MaxAge)
+
+	for i, f := range files {
+		if r.opts.MaxFiles > 0 && i >= r.opts.MaxFiles {
+			os.Remove(f)
+			continue
+		}
+
+		if r.opts.MaxAge > 0 {
+			fi, err := os.Stat(f)
+			if err == nil && fi.ModTime().Before(cutoff) {
+				os.Remove(f)
+			}
+		}
+	}
+}
+
+func (r *RotatingFile) handleSIGHUP() {
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-r.sighup:
+			// nowhere to report the error, the next write will surface it
+			_ = r.Reopen()
+		}
+	}
+}
+
+// gzipFile compresses fileName to fileName.gz and removes the original.
+func gzipFile(fileName string) error {
+	in, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := fileName + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, fileName+".gz"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Remove(fileName)
+}
@@ -0,0 +1,290 @@
+package fileutils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func readRotated(t *testing.T, fileName string) string {
+	t.Helper()
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(fileName, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	targetFile := filepath.Join(t.TempDir(), "app.log")
+
+	r, err := NewRotatingFile(targetFile, &RotateOptions{MaxSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := fmt.Fprintf(r, "line %d\n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := r.rotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", files)
+	}
+
+	tests := map[string]string{
+		targetFile: "line 2\n",
+		files[0]:   "line 1\n",
+		files[1]:   "line 0\n",
+	}
+
+	for fileName, expected := range tests {
+		actual := readRotated(t, fileName)
+		if expected != actual {
+			t.Errorf("%v: expected %q, got %q", fileName, expected, actual)
+		}
+	}
+}
+
+func TestRotatingFileInterval(t *testing.T) {
+	targetFile := filepath.Join(t.TempDir(), "app.log")
+	now := time.Date(2023, 3, 27, 10, 0, 0, 0, time.UTC)
+
+	r := &RotatingFile{
+		fileName: targetFile,
+		opts:     RotateOptions{Interval: time.Hour},
+		now:      func() time.Time { return now },
+		done:     make(chan struct{}),
+	}
+	err := r.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []time.Duration{0, 30 * time.Minute, time.Hour, 3 * time.Hour}
+	for _, step := range steps {
+		now = time.Date(2023, 3, 27, 10, 0, 0, 0, time.UTC).Add(step)
+		if _, err := r.Write([]byte("x\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := r.rotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		targetFile + ".20230327T130000.000",
+		targetFile + ".20230327T110000.000",
+	}
+
+	if strings.Join(expected, ",") != strings.Join(files, ",") {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+}
+
+func TestRotatingFileCompressAndPrune(t *testing.T) {
+	targetFile := filepath.Join(t.TempDir(), "app.log")
+
+	r, err := NewRotatingFile(targetFile, &RotateOptions{Compress: true, MaxFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := fmt.Fprintf(r, "line %d\n", i); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := r.rotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", files)
+	}
+
+	for i, fileName := range files {
+		if !strings.HasSuffix(fileName, ".gz") {
+			t.Errorf("expected %v to be compressed", fileName)
+		}
+
+		expected := fmt.Sprintf("line %d\n", 3-i)
+		if actual := readRotated(t, fileName); expected != actual {
+			t.Errorf("%v: expected %q, got %q", fileName, expected, actual)
+		}
+	}
+}
+
+func TestRotatingFileConcurrentWrites(t *testing.T) {
+	targetFile := filepath.Join(t.TempDir(), "app.log")
+	line := "0123456789\n"
+	writers, writes := 8, 50
+
+	r, err := NewRotatingFile(targetFile, &RotateOptions{MaxSize: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				if _, err := r.Write([]byte(line)); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	err = r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := r.rotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total int
+	for _, fileName := range append(files, targetFile) {
+		content := readRotated(t, fileName)
+		if len(content) > 200 {
+			t.Errorf("%v: exceeds max size [%v]", fileName, len(content))
+		}
+		total += strings.Count(content, line)
+	}
+
+	if total != writers*writes {
+		t.Errorf("expected %v lines, got %v", writers*writes, total)
+	}
+}
+
+func TestRotatingFileSIGHUP(t *testing.T) {
+	targetFile := filepath.Join(t.TempDir(), "app.log")
+
+	r, err := NewRotatingFile(targetFile, &RotateOptions{ReopenOnSIGHUP: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	_, err = r.Write([]byte("before\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// external rotation
+	err = os.Rename(targetFile, targetFile+".old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !FileExists(targetFile) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_, err = r.Write([]byte("after\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := readRotated(t, targetFile); actual != "after\n" {
+		t.Errorf("expected reopened file to contain after, got %q", actual)
+	}
+}
+
+func TestRotatingFileReopenFailure(t *testing.T) {
+	targetFile := filepath.Join(t.TempDir(), "app.log")
+
+	r, err := NewRotatingFile(targetFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// something that cannot be opened for writing takes the file's place
+	err = os.Rename(targetFile, targetFile+".old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Mkdir(targetFile, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Reopen(); err == nil {
+		t.Errorf("expected error reopening, got nil")
+	}
+
+	// writes carry on to the file that was open
+	_, err = r.Write([]byte("kept\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := readRotated(t, targetFile+".old"); actual != "kept\n" {
+		t.Errorf("expected writes to go to the open file, got %q", actual)
+	}
+}
@@ -0,0 +1,249 @@
+package fileutils
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+type ConflictPolicy string
+
+const (
+	ConflictFail      ConflictPolicy = "fail"
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+)
+
+type ScaffoldAction string
+
+const (
+	ScaffoldCreate    ScaffoldAction = "create"
+	ScaffoldOverwrite ScaffoldAction = "overwrite"
+	ScaffoldSkip      ScaffoldAction = "skip"
+)
+
+// template files may carry this suffix, which is removed from their names
+const scaffoldTemplateExt = ".tmpl"
+
+type ScaffoldOptions struct {
+	// passed to every template
+	Data  any
+	Funcs template.FuncMap
+	// what to do when a file already exists, defaults to ConflictFail
+	Conflict ConflictPolicy
+	// copy files matching one of these Glob patterns, relative to the
+	// template root, without rendering their contents
+	Raw []string
+	// work out what would be written without touching dst
+	DryRun bool
+}
+
+type ScaffoldEntry struct {
+	// destination path
+	Path string
+	// template path within the source fs.FS
+	Source string
+	Dir    bool
+	Mode   fs.FileMode
+	Action ScaffoldAction
+}
+
+type scaffoldPlan struct {
+	ScaffoldEntry
+	content []byte
+}
+
+// Scaffold copies the template tree at root in src, which may be an
+// embed.FS, to dst. Path names and the contents of text files are rendered
+// with text/template, a rendered path component that is empty leaves that
+// file or folder out, and a .tmpl suffix is dropped. Modes are kept, with
+// the owner always able to write, since embed.FS reports everything as read
+// only. Every template is rendered and checked for conflicts before anything
+// is written, so a failure leaves dst untouched. It returns an entry per file
+// and folder, in the order they are created.
+func Scaffold(src fs.FS, root, dst string, opts *ScaffoldOptions) ([]ScaffoldEntry, error) {
+	var funcName string = "Scaffold"
+
+	if opts == nil {
+		opts = &ScaffoldOptions{}
+	}
+
+	conflict := opts.Conflict
+	if conflict == "" {
+		conflict = ConflictFail
+	}
+
+	var raw *globMatcher
+	if len(opts.Raw) > 0 {
+		var err error
+		raw, err = newGlobMatcher(opts.Raw)
+		if err != nil {
+			return nil, newError(funcName, root, "invalid raw pattern", err)
+		}
+	}
+
+	var plan []scaffoldPlan
+
+	err := fs.WalkDir(src, root, func(s string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+
+		if s == root {
+			return nil
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(s, root), "/")
+		if root == "." {
+			rel = s
+		}
+
+		target, err := renderScaffoldPath(rel, opts)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		p := scaffoldPlan{ScaffoldEntry: ScaffoldEntry{
+			Path:   filepath.Join(dst, filepath.FromSlash(target)),
+			Source: s,
+			Dir:    d.IsDir(),
+			Mode:   fi.Mode().Perm() | 0200,
+			Action: ScaffoldCreate,
+		}}
+
+		if p.Dir {
+			p.Mode |= 0100
+		} else {
+			p.content, err = fs.ReadFile(src, s)
+			if err != nil {
+				return err
+			}
+
+			if (raw == nil || !raw.visit(rel)) && !detectType(sniffHead(p.content)).Binary {
+				p.content, err = renderScaffold(s, string(p.content), opts)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		existing, err := os.Stat(p.Path)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return err
+		case existing.IsDir() != p.Dir:
+			return newTargetError(funcName, p.Path, s, "file and folder conflict", ErrExist)
+		case p.Dir:
+			p.Action = ScaffoldSkip
+		case conflict == ConflictSkip:
+			p.Action = ScaffoldSkip
+		case conflict == ConflictOverwrite:
+			p.Action = ScaffoldOverwrite
+		default:
+			return newTargetError(funcName, p.Path, s, "", ErrExist)
+		}
+
+		plan = append(plan, p)
+
+		return nil
+	})
+	if err != nil {
+		return nil, newTargetError(funcName, root, dst, "error preparing templates", err)
+	}
+
+	entries := make([]ScaffoldEntry, len(plan))
+	for i, p := range plan {
+		entries[i] = p.ScaffoldEntry
+	}
+
+	if opts.DryRun {
+		return entries, nil
+	}
+
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return nil, newError(funcName, dst, "error creating folder", err)
+	}
+
+	for _, p := range plan {
+		if p.Action == ScaffoldSkip {
+			continue
+		}
+
+		if p.Dir {
+			if err := os.Mkdir(p.Path, p.Mode); err != nil {
+				return entries, newError(funcName, p.Path, "error creating folder", err)
+			}
+			continue
+		}
+
+		err := writeFileAtomic(p.Path, p.Mode, func(w io.Writer) error {
+			_, err := w.Write(p.content)
+			return err
+		})
+		if err != nil {
+			return entries, newError(funcName, p.Path, "error writing file", err)
+		}
+	}
+
+	return entries, nil
+}
+
+// renderScaffoldPath renders each component of rel, returning an empty path
+// when any of them renders empty.
+func renderScaffoldPath(rel string, opts *ScaffoldOptions) (string, error) {
+	parts := strings.Split(rel, "/")
+
+	for i, part := range parts {
+		b, err := renderScaffold(rel, part, opts)
+		if err != nil {
+			return "", err
+		}
+		if len(b) == 0 {
+			return "", nil
+		}
+
+		parts[i] = string(b)
+	}
+
+	target := strings.TrimSuffix(path.Join(parts...), scaffoldTemplateExt)
+	if !fs.ValidPath(target) {
+		return "", newTargetError("Scaffold", rel, target, "rendered path leaves the destination", ErrInvalid)
+	}
+
+	return target, nil
+}
+
+func renderScaffold(name, text string, opts *ScaffoldOptions) ([]byte, error) {
+	t, err := template.New(name).Funcs(opts.Funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := t.Execute(&b, opts.Data); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
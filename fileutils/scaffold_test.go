@@ -0,0 +1,174 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+)
+
+func TestScaffold(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "out")
+	fsys := fstest.MapFS{
+		"skel":                                 {Mode: os.ModeDir | 0555},
+		"skel/README.md.tmpl":                  {Data: []byte("# {{.Name}}\n"), Mode: 0444},
+		"skel/cmd/{{.Name}}/main.go":           {Data: []byte("package main // {{upper .Name}}\n"), Mode: 0444},
+		"skel/scripts/run.sh":                  {Data: []byte("#!/bin/sh\necho {{.Name}}\n"), Mode: 0555},
+		"skel/raw/literal.txt":                 {Data: []byte("{{not rendered}}\n"), Mode: 0444},
+		"skel/{{if .Docker}}Dockerfile{{end}}": {Data: []byte("FROM scratch\n"), Mode: 0444},
+		"skel/logo.bin":                        {Data: []byte("\x00\x01{{.Name}}"), Mode: 0444},
+	}
+	opts := &ScaffoldOptions{
+		Data:  map[string]any{"Name": "svc", "Docker": false},
+		Funcs: template.FuncMap{"upper": strings.ToUpper},
+		Raw:   []string{"raw/**"},
+	}
+
+	opts.DryRun = true
+	entries, err := Scaffold(fsys, "skel", dst, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if FolderExists(dst) {
+		t.Errorf("expected dry run not to create %v", dst)
+	}
+
+	var paths []string
+	for _, e := range entries {
+		rel, _ := filepath.Rel(dst, e.Path)
+		paths = append(paths, filepath.ToSlash(rel))
+		if e.Action != ScaffoldCreate {
+			t.Errorf("%v: expected create, got %v", rel, e.Action)
+		}
+	}
+
+	expected := []string{"README.md", "cmd", "cmd/svc", "cmd/svc/main.go", "logo.bin", "raw", "raw/literal.txt", "scripts", "scripts/run.sh"}
+	if !reflect.DeepEqual(expected, paths) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+
+	opts.DryRun = false
+	if _, err := Scaffold(fsys, "skel", dst, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]struct {
+		content string
+		mode    os.FileMode
+	}{
+		"README.md":       {"# svc\n", 0644},
+		"cmd/svc/main.go": {"package main // SVC\n", 0644},
+		"scripts/run.sh":  {"#!/bin/sh\necho svc\n", 0755},
+		"raw/literal.txt": {"{{not rendered}}\n", 0644},
+		"logo.bin":        {"\x00\x01{{.Name}}", 0644},
+	}
+
+	for name, f := range files {
+		fileName := filepath.Join(dst, name)
+
+		contents, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(contents) != f.content {
+			t.Errorf("%s: expected %q, got %q", name, f.content, contents)
+		}
+
+		fi, err := os.Stat(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != f.mode {
+			t.Errorf("%s: expected mode %v, got %v", name, f.mode, fi.Mode().Perm())
+		}
+	}
+}
+
+func TestScaffoldConflicts(t *testing.T) {
+	dst := t.TempDir()
+	fsys := fstest.MapFS{
+		"skel/README.md.tmpl":        {Data: []byte("# {{.Name}}\n"), Mode: 0444},
+		"skel/cmd/{{.Name}}/main.go": {Data: []byte("package main\n"), Mode: 0444},
+	}
+	opts := &ScaffoldOptions{Data: map[string]any{"Name": "svc"}}
+
+	readme := filepath.Join(dst, "README.md")
+	err := os.WriteFile(readme, []byte("existing\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Scaffold(fsys, "skel", dst, opts)
+	if !errors.Is(err, ErrExist) {
+		t.Errorf("expected ErrExist, got %v", err)
+	}
+	if FolderExists(filepath.Join(dst, "cmd")) {
+		t.Errorf("expected a conflict to leave the destination untouched")
+	}
+
+	opts.Conflict = ConflictSkip
+	entries, err := Scaffold(fsys, "skel", dst, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].Action != ScaffoldSkip {
+		t.Errorf("expected README.md to be skipped, got %v", entries[0].Action)
+	}
+
+	contents, err := os.ReadFile(readme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "existing\n" {
+		t.Errorf("expected skip to keep the file, got %q", contents)
+	}
+
+	opts.Conflict = ConflictOverwrite
+	entries, err = Scaffold(fsys, "skel", dst, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].Action != ScaffoldOverwrite {
+		t.Errorf("expected README.md to be overwritten, got %v", entries[0].Action)
+	}
+
+	contents, err = os.ReadFile(readme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "# svc\n" {
+		t.Errorf("expected overwrite to render the file, got %q", contents)
+	}
+}
+
+func TestScaffoldErrors(t *testing.T) {
+	dst := t.TempDir()
+
+	tests := map[string]struct {
+		fsys fstest.MapFS
+		data any
+	}{
+		"missing key": {
+			fsys: fstest.MapFS{"a.txt": {Data: []byte("{{.Missing}}")}},
+			data: map[string]any{},
+		},
+		"bad template": {
+			fsys: fstest.MapFS{"a.txt": {Data: []byte("{{.Name")}},
+		},
+		"escaping path": {
+			fsys: fstest.MapFS{"{{.Name}}/a.txt": {Data: []byte("a")}},
+			data: map[string]any{"Name": ".."},
+		},
+	}
+
+	for name, tt := range tests {
+		_, err := Scaffold(tt.fsys, ".", dst, &ScaffoldOptions{Data: tt.data})
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
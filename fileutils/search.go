@@ -0,0 +1,310 @@
+package fileutils
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type SearchOptions struct {
+	// treat patterns as literal text rather than regular expressions
+	Literal    bool
+	IgnoreCase bool
+	// only search files matching one of these Glob patterns, relative to the
+	// root, all files when empty
+	Include []string
+	// skip files larger than MaxFileSize bytes, zero means no limit
+	MaxFileSize int64
+	// stop once MaxMatches matches have been sent, zero means no limit
+	MaxMatches int
+	// lines of context to include before and after each match
+	Context int
+	// files searched in parallel, defaults to runtime.NumCPU
+	Workers int
+	// called, possibly concurrently, for each file or folder that could not
+	// be searched, which are otherwise skipped silently
+	OnError func(err error)
+}
+
+type SearchMatch struct {
+	Path string
+	// 1 based line number and byte column of the match
+	Line   int
+	Column int
+	Match  string
+	// the whole line, without its line ending
+	Text   string
+	Before []string
+	After  []string
+}
+
+var errSearchStopped = errors.New("search stopped")
+
+type searcher struct {
+	// first so it is 64 bit aligned for the atomic adds on 32 bit platforms
+	sent int64
+
+	root  string
+	re    *regexp.Regexp
+	opts  SearchOptions
+	globs *globMatcher
+
+	matches chan<- SearchMatch
+	stop    context.CancelFunc
+}
+
+// Search looks for lines matching any of patterns in the regular files below
+// root, skipping binary files, and streams the matches. Files are searched in
+// parallel so matches from different files interleave, matches within a file
+// arrive in order. Both channels are closed when the search is done, ctx is
+// done or MaxMatches is reached. An error that stops the search is sent
+// first.
+func Search(ctx context.Context, root string, patterns []string, opts *SearchOptions) (<-chan SearchMatch, <-chan error) {
+	matches := make(chan SearchMatch)
+	errs := make(chan error, 1)
+
+	s := &searcher{root: root, matches: matches}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Workers <= 0 {
+		s.opts.Workers = runtime.NumCPU()
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(matches)
+
+		if err := s.run(ctx, patterns); err != nil {
+			errs <- err
+		}
+	}()
+
+	return matches, errs
+}
+
+func (s *searcher) run(ctx context.Context, patterns []string) error {
+	var funcName string = "Search"
+
+	if len(patterns) == 0 {
+		return newError(funcName, s.root, "no patterns", ErrInvalid)
+	}
+
+	exprs := make([]string, len(patterns))
+	for i, p := range patterns {
+		if s.opts.Literal {
+			p = regexp.QuoteMeta(p)
+		}
+		exprs[i] = "(?:" + p + ")"
+	}
+
+	expr := strings.Join(exprs, "|")
+	if s.opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+
+	var err error
+	s.re, err = regexp.Compile(expr)
+	if err != nil {
+		return newError(funcName, s.root, "invalid pattern", err)
+	}
+
+	if len(s.opts.Include) > 0 {
+		s.globs, err = newGlobMatcher(s.opts.Include)
+		if err != nil {
+			return newError(funcName, s.root, "invalid include pattern", err)
+		}
+	}
+
+	if !FolderExists(s.root) {
+		return newError(funcName, s.root, "", ErrNotExist)
+	}
+
+	if !IsFolder(s.root) {
+		return newError(funcName, s.root, "", ErrNotFolder)
+	}
+
+	ctx, s.stop = context.WithCancel(ctx)
+	defer s.stop()
+
+	files := make(chan string)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fileName := range files {
+				if err := s.searchFile(ctx, fileName); err != nil {
+					s.report(newError(funcName, fileName, "error searching file", err))
+				}
+			}
+		}()
+	}
+
+	err = s.walk(ctx, files)
+	close(files)
+	wg.Wait()
+
+	if err != nil && !errors.Is(err, errSearchStopped) {
+		return newError(funcName, s.root, "error walking target", err)
+	}
+
+	return nil
+}
+
+func (s *searcher) walk(ctx context.Context, files chan<- string) error {
+	return filepath.WalkDir(s.root, func(p string, d fs.DirEntry, e error) error {
+		if e != nil {
+			if p == s.root {
+				return e
+			}
+			s.report(newError("Search", p, "error reading target", e))
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return errSearchStopped
+		}
+
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if p != s.root && s.globs != nil && s.globs.prune(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() || (s.globs != nil && !s.globs.visit(rel)) {
+			return nil
+		}
+
+		select {
+		case files <- p:
+		case <-ctx.Done():
+			return errSearchStopped
+		}
+
+		return nil
+	})
+}
+
+func (s *searcher) searchFile(ctx context.Context, fileName string) error {
+	f, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if s.opts.MaxFileSize > 0 {
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if fi.Size() > s.opts.MaxFileSize {
+			return nil
+		}
+	}
+
+	ft, r, err := PeekType(f)
+	if err != nil {
+		return err
+	}
+	if ft.Binary {
+		return nil
+	}
+
+	var before []string
+	var pending []*SearchMatch
+	var lineNo int
+
+	err = readLines(r, func(line string) error {
+		if ctx.Err() != nil {
+			return errSearchStopped
+		}
+		lineNo++
+
+		for len(pending) > 0 && len(pending[0].After) == s.opts.Context {
+			if err := s.send(ctx, pending[0]); err != nil {
+				return err
+			}
+			pending = pending[1:]
+		}
+		for _, m := range pending {
+			m.After = append(m.After, line)
+		}
+
+		for _, loc := range s.re.FindAllStringIndex(line, -1) {
+			m := &SearchMatch{
+				Path:   fileName,
+				Line:   lineNo,
+				Column: loc[0] + 1,
+				Match:  line[loc[0]:loc[1]],
+				Text:   line,
+			}
+			if len(before) > 0 {
+				m.Before = append([]string(nil), before...)
+			}
+			pending = append(pending, m)
+		}
+
+		if s.opts.Context > 0 {
+			before = append(before, line)
+			if len(before) > s.opts.Context {
+				before = before[1:]
+			}
+		}
+
+		return nil
+	})
+
+	for err == nil && len(pending) > 0 {
+		err = s.send(ctx, pending[0])
+		pending = pending[1:]
+	}
+
+	if errors.Is(err, errSearchStopped) {
+		return nil
+	}
+
+	return err
+}
+
+// send delivers m unless the search has been stopped or has already sent
+// MaxMatches matches.
+func (s *searcher) send(ctx context.Context, m *SearchMatch) error {
+	if s.opts.MaxMatches > 0 {
+		n := atomic.AddInt64(&s.sent, 1)
+		if n > int64(s.opts.MaxMatches) {
+			return errSearchStopped
+		}
+		if n == int64(s.opts.MaxMatches) {
+			defer s.stop()
+		}
+	}
+
+	select {
+	case s.matches <- *m:
+		return nil
+	case <-ctx.Done():
+		return errSearchStopped
+	}
+}
+
+func (s *searcher) report(err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
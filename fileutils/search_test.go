@@ -0,0 +1,182 @@
+package fileutils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func collectSearch(t *testing.T, matches <-chan SearchMatch, errs <-chan error) []SearchMatch {
+	var actual []SearchMatch
+	for m := range matches {
+		actual = append(actual, m)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Slice(actual, func(i, j int) bool {
+		if actual[i].Path != actual[j].Path {
+			return actual[i].Path < actual[j].Path
+		}
+		if actual[i].Line != actual[j].Line {
+			return actual[i].Line < actual[j].Line
+		}
+		return actual[i].Column < actual[j].Column
+	})
+
+	return actual
+}
+
+func TestSearch(t *testing.T) {
+	dir := testTree(t, fstest.MapFS{
+		"a.txt":         {Data: []byte("one\nsecret=abc\nthree\nfour\n")},
+		"sub/b.conf":    {Data: []byte("SECRET=def\nsecret=ghi secret=jkl\n")},
+		"sub/c.txt":     {Data: []byte("nothing here\n")},
+		"bin/data.bin":  {Data: []byte("secret=\x00\x01\x02\x03")},
+		"large/big.txt": {Data: []byte(strings.Repeat("x", 2048) + "\nsecret=big\n")},
+	})
+
+	tests := map[string]struct {
+		patterns []string
+		opts     *SearchOptions
+		expected []string
+	}{
+		"regex": {
+			patterns: []string{`secret=\w+`},
+			expected: []string{"a.txt:2:1:secret=abc", "large/big.txt:2:1:secret=big", "sub/b.conf:2:1:secret=ghi", "sub/b.conf:2:12:secret=jkl"},
+		},
+		"literal ignore case": {
+			patterns: []string{"SECRET=d", "three"},
+			opts:     &SearchOptions{Literal: true, IgnoreCase: true},
+			expected: []string{"a.txt:3:1:three", "sub/b.conf:1:1:SECRET=d"},
+		},
+		"include and size limit": {
+			patterns: []string{"secret"},
+			opts:     &SearchOptions{Include: []string{"**/*.txt"}, MaxFileSize: 1024},
+			expected: []string{"a.txt:2:1:secret"},
+		},
+		"literal metacharacters": {
+			patterns: []string{"secret=abc."},
+			opts:     &SearchOptions{Literal: true},
+		},
+	}
+
+	for name, tt := range tests {
+		matches, errs := Search(context.Background(), dir, tt.patterns, tt.opts)
+
+		var actual []string
+		for _, m := range collectSearch(t, matches, errs) {
+			rel, _ := filepath.Rel(dir, m.Path)
+			actual = append(actual, strings.Join([]string{filepath.ToSlash(rel), strconv.Itoa(m.Line), strconv.Itoa(m.Column), m.Match}, ":"))
+		}
+
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%s: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestSearchContext(t *testing.T) {
+	dir := testTree(t, fstest.MapFS{
+		"a.txt":     {Data: []byte("one\nsecret=abc\nthree\nfour\n")},
+		"sub/c.txt": {Data: []byte("nothing here\n")},
+	})
+
+	matches, errs := Search(context.Background(), dir, []string{"secret"}, &SearchOptions{Include: []string{"a.txt"}, Context: 2})
+	actual := collectSearch(t, matches, errs)
+
+	if len(actual) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(actual))
+	}
+
+	m := actual[0]
+	if m.Text != "secret=abc" {
+		t.Errorf("expected line text, got %q", m.Text)
+	}
+	if !reflect.DeepEqual([]string{"one"}, m.Before) {
+		t.Errorf("expected before context [one], got %v", m.Before)
+	}
+	if !reflect.DeepEqual([]string{"three", "four"}, m.After) {
+		t.Errorf("expected after context [three four], got %v", m.After)
+	}
+}
+
+func TestSearchMaxMatches(t *testing.T) {
+	dir := t.TempDir()
+
+	for i := 0; i < 20; i++ {
+		fileName := filepath.Join(dir, "file"+strconv.Itoa(i)+".txt")
+		if err := os.WriteFile(fileName, []byte(strings.Repeat("match\n", 50)), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matches, errs := Search(context.Background(), dir, []string{"match"}, &SearchOptions{MaxMatches: 7, Workers: 4})
+	if n := len(collectSearch(t, matches, errs)); n != 7 {
+		t.Errorf("expected 7 matches, got %d", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	matches, errs = Search(ctx, dir, []string{"match"}, nil)
+	<-matches
+	cancel()
+	for range matches {
+	}
+	if err := <-errs; err != nil {
+		t.Errorf("expected no error after cancel, got %v", err)
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	dir := testTree(t, fstest.MapFS{
+		"a.txt":     {Data: []byte("one\nsecret=abc\n")},
+		"sub/c.txt": {Data: []byte("nothing here\n")},
+	})
+
+	matches, errs := Search(context.Background(), dir, []string{"("}, nil)
+	for range matches {
+	}
+	if err := <-errs; err == nil {
+		t.Errorf("expected error for a bad pattern, got nil")
+	}
+
+	matches, errs = Search(context.Background(), filepath.Join(dir, "nofolder"), []string{"x"}, nil)
+	for range matches {
+	}
+	if err := <-errs; !errors.Is(err, ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+
+	if os.Geteuid() == 0 {
+		return
+	}
+
+	err := os.Chmod(filepath.Join(dir, "sub", "c.txt"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var reported []error
+	opts := &SearchOptions{OnError: func(err error) {
+		mu.Lock()
+		reported = append(reported, err)
+		mu.Unlock()
+	}}
+
+	matches, errs = Search(context.Background(), dir, []string{"secret"}, opts)
+	collectSearch(t, matches, errs)
+
+	if len(reported) != 1 || !errors.Is(reported[0], os.ErrPermission) {
+		t.Errorf("expected one permission error, got %v", reported)
+	}
+}
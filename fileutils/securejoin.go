@@ -0,0 +1,88 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SecureJoin joins unsafePath onto root, resolving ".." and symlinks as if
+// root were the filesystem root, so the result can never point outside it.
+// Components that do not exist are joined lexically.
+//
+// The result is only safe until something else changes the tree, use Root
+// when the paths being resolved are under an attacker's control.
+func SecureJoin(root, unsafePath string) (string, error) {
+	var funcName string = "SecureJoin"
+
+	root = filepath.Clean(root)
+
+	var resolved string
+	var hops int
+
+	parts := splitPath(unsafePath)
+	for len(parts) > 0 {
+		comp := parts[0]
+		parts = parts[1:]
+
+		if comp == ".." {
+			resolved = parentPath(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, comp)
+
+		fi, err := os.Lstat(filepath.Join(root, next))
+		if os.IsNotExist(err) {
+			resolved = next
+			continue
+		}
+		if err != nil {
+			return "", newError(funcName, filepath.Join(root, next), "error checking file info", err)
+		}
+
+		if fi.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		hops++
+		if hops > maxSymlinkHops {
+			return "", newError(funcName, unsafePath, "", ErrSymlinkLoop)
+		}
+
+		dest, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			return "", newError(funcName, filepath.Join(root, next), "error reading link", err)
+		}
+
+		if filepath.IsAbs(dest) {
+			resolved = ""
+		}
+		parts = append(splitPath(dest), parts...)
+	}
+
+	return filepath.Join(root, resolved), nil
+}
+
+// splitPath breaks p into its components, dropping empty and "." entries.
+func splitPath(p string) []string {
+	var parts []string
+
+	for _, comp := range strings.Split(filepath.ToSlash(p), "/") {
+		if comp != "" && comp != "." {
+			parts = append(parts, comp)
+		}
+	}
+
+	return parts
+}
+
+func parentPath(p string) string {
+	p = filepath.Dir(p)
+	if p == "." {
+		return ""
+	}
+
+	return p
+}
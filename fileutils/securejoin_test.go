@@ -0,0 +1,67 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecureJoin(t *testing.T) {
+	root := t.TempDir()
+
+	err := MkDir(filepath.Join(root, "a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := map[string]string{
+		"a/up":       "../../../etc",
+		"a/abs":      "/etc",
+		"a/sibling":  "b",
+		"a/loop":     "loop",
+		"a/b/parent": "..",
+	}
+	for name, target := range links {
+		err := os.Symlink(target, filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]struct {
+		path        string
+		expected    string
+		shouldError bool
+	}{
+		"plain":               {path: "a/b/file.txt", expected: "a/b/file.txt"},
+		"dot dot":             {path: "../../etc/passwd", expected: "etc/passwd"},
+		"inner dot dot":       {path: "a/../../a/b", expected: "a/b"},
+		"absolute":            {path: "/a/b", expected: "a/b"},
+		"relative link up":    {path: "a/up/passwd", expected: "etc/passwd"},
+		"absolute link":       {path: "a/abs/passwd", expected: "etc/passwd"},
+		"link to sibling":     {path: "a/sibling/file.txt", expected: "a/b/file.txt"},
+		"link to parent":      {path: "a/b/parent/b", expected: "a/b"},
+		"missing then up":     {path: "a/missing/../b", expected: "a/b"},
+		"empty":               {path: "", expected: ""},
+		"symlink loop errors": {path: "a/loop", shouldError: true},
+	}
+
+	for name, tt := range tests {
+		actual, err := SecureJoin(root, tt.path)
+
+		if tt.shouldError {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		expected := filepath.Join(root, tt.expected)
+		if expected != actual {
+			t.Errorf("%s: expected %v, got %v", name, expected, actual)
+		}
+	}
+}
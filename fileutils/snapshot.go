@@ -0,0 +1,289 @@
+package fileutils
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+type SnapshotOptions struct {
+	// record the SHA-256 of every regular file, in the FileHash format
+	Hash bool
+	// reuse the hashes of files whose size, mtime and inode have not changed
+	// since this snapshot was taken
+	Previous *Snapshot
+	// only record paths matching one of these Glob patterns, relative to the
+	// root, everything when empty
+	Include []string
+}
+
+type SnapshotEntry struct {
+	// slash separated and relative to the root
+	Path    string      `json:"path"`
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"mtime"`
+	Mode    fs.FileMode `json:"mode"`
+	// zero where the platform has no inodes
+	Inode  uint64 `json:"inode,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Snapshot records the state of a tree at a point in time, so a later scan
+// can be compared with it. Symlinks are recorded, not followed.
+type Snapshot struct {
+	Root    string          `json:"root"`
+	Taken   time.Time       `json:"taken"`
+	Entries []SnapshotEntry `json:"entries"`
+}
+
+type SnapshotRename struct {
+	From SnapshotEntry
+	To   SnapshotEntry
+}
+
+// SnapshotDiff lists what changed between two snapshots, each list sorted by
+// path.
+type SnapshotDiff struct {
+	Created  []SnapshotEntry
+	Modified []SnapshotEntry
+	Deleted  []SnapshotEntry
+	Renamed  []SnapshotRename
+}
+
+func (d SnapshotDiff) Empty() bool {
+	return len(d.Created) == 0 && len(d.Modified) == 0 && len(d.Deleted) == 0 && len(d.Renamed) == 0
+}
+
+// TakeSnapshot records every file, folder and symlink below root.
+func TakeSnapshot(root string, opts *SnapshotOptions) (*Snapshot, error) {
+	var funcName string = "TakeSnapshot"
+
+	if opts == nil {
+		opts = &SnapshotOptions{}
+	}
+
+	var globs *globMatcher
+	if len(opts.Include) > 0 {
+		var err error
+		globs, err = newGlobMatcher(opts.Include)
+		if err != nil {
+			return nil, newError(funcName, root, "invalid include pattern", err)
+		}
+	}
+
+	if !FolderExists(root) {
+		return nil, newError(funcName, root, "", ErrNotExist)
+	}
+
+	if !IsFolder(root) {
+		return nil, newError(funcName, root, "", ErrNotFolder)
+	}
+
+	var previous map[string]SnapshotEntry
+	if opts.Previous != nil {
+		previous = opts.Previous.index()
+	}
+
+	snap := &Snapshot{Root: root, Taken: time.Now()}
+
+	err := filepath.WalkDir(root, func(s string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+
+		if s == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, s)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if globs != nil {
+			if d.IsDir() && globs.prune(rel) {
+				return filepath.SkipDir
+			}
+			if !globs.visit(rel) {
+				return nil
+			}
+		}
+
+		fi, err := d.Info()
+		if os.IsNotExist(err) {
+			// removed since the folder was read
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entry := SnapshotEntry{
+			Path:    rel,
+			Size:    fi.Size(),
+			ModTime: fi.ModTime(),
+			Mode:    fi.Mode(),
+			Inode:   fileInode(fi),
+		}
+
+		if opts.Hash && fi.Mode().IsRegular() {
+			if p, ok := previous[rel]; ok && p.SHA256 != "" && p.sameFile(entry) {
+				entry.SHA256 = p.SHA256
+			} else if entry.SHA256, err = FileHash(s); err != nil {
+				return err
+			}
+		}
+
+		snap.Entries = append(snap.Entries, entry)
+
+		return nil
+	})
+	if err != nil {
+		return nil, newError(funcName, root, "error walking target", err)
+	}
+
+	return snap, nil
+}
+
+func LoadSnapshot(fileName string) (*Snapshot, error) {
+	var funcName string = "LoadSnapshot"
+
+	snap, err := ReadJSON[*Snapshot](fileName)
+	if err != nil {
+		return nil, newError(funcName, fileName, "error reading snapshot", err)
+	}
+
+	if snap == nil {
+		return nil, newError(funcName, fileName, "empty snapshot", ErrInvalid)
+	}
+
+	return snap, nil
+}
+
+// Save atomically writes the snapshot to fileName as JSON.
+func (s *Snapshot) Save(fileName string) error {
+	var funcName string = "Snapshot.Save"
+
+	if err := WriteJSON(fileName, s, false); err != nil {
+		return newError(funcName, fileName, "error writing snapshot", err)
+	}
+
+	return nil
+}
+
+// Diff compares a later snapshot of the same tree with s. A path whose size,
+// mtime, mode, inode or hash differs is modified. Files that disappeared from
+// one path and appeared at another are renamed when both snapshots have the
+// same hash for them, or the same inode, size and mtime.
+func (s *Snapshot) Diff(later *Snapshot) SnapshotDiff {
+	var diff SnapshotDiff
+
+	before := s.index()
+	after := later.index()
+
+	var deleted, created []SnapshotEntry
+
+	for _, e := range s.Entries {
+		if _, ok := after[e.Path]; !ok {
+			deleted = append(deleted, e)
+		}
+	}
+
+	for _, e := range later.Entries {
+		old, ok := before[e.Path]
+		if !ok {
+			created = append(created, e)
+			continue
+		}
+
+		if !old.sameFile(e) || old.Mode != e.Mode || (old.SHA256 != "" && e.SHA256 != "" && old.SHA256 != e.SHA256) {
+			// folder mtimes change whenever their contents do
+			if e.Mode.IsDir() && old.Mode == e.Mode {
+				continue
+			}
+			diff.Modified = append(diff.Modified, e)
+		}
+	}
+
+	// candidates for each deleted file, looked up by hash and by inode
+	byHash := make(map[string][]int)
+	byInode := make(map[uint64][]int)
+	for i, c := range created {
+		if !c.Mode.IsRegular() {
+			continue
+		}
+		if c.SHA256 != "" {
+			byHash[c.SHA256] = append(byHash[c.SHA256], i)
+		}
+		if c.Inode != 0 {
+			byInode[c.Inode] = append(byInode[c.Inode], i)
+		}
+	}
+
+	matched := make(map[int]bool)
+	for _, d := range deleted {
+		found := -1
+		if d.Mode.IsRegular() {
+			for _, i := range append(byHash[d.SHA256], byInode[d.Inode]...) {
+				if !matched[i] && d.renamedTo(created[i]) {
+					found = i
+					break
+				}
+			}
+		}
+
+		if found < 0 {
+			diff.Deleted = append(diff.Deleted, d)
+			continue
+		}
+
+		matched[found] = true
+		diff.Renamed = append(diff.Renamed, SnapshotRename{From: d, To: created[found]})
+	}
+
+	for i, c := range created {
+		if !matched[i] {
+			diff.Created = append(diff.Created, c)
+		}
+	}
+
+	sortSnapshotEntries(diff.Created)
+	sortSnapshotEntries(diff.Modified)
+	sortSnapshotEntries(diff.Deleted)
+	sort.Slice(diff.Renamed, func(i, j int) bool {
+		return diff.Renamed[i].To.Path < diff.Renamed[j].To.Path
+	})
+
+	return diff
+}
+
+func (s *Snapshot) index() map[string]SnapshotEntry {
+	m := make(map[string]SnapshotEntry, len(s.Entries))
+	for _, e := range s.Entries {
+		m[e.Path] = e
+	}
+
+	return m
+}
+
+// sameFile reports whether e and o look like the same unchanged file.
+func (e SnapshotEntry) sameFile(o SnapshotEntry) bool {
+	return e.Size == o.Size && e.ModTime.Equal(o.ModTime) && e.Inode == o.Inode
+}
+
+func (e SnapshotEntry) renamedTo(o SnapshotEntry) bool {
+	if e.SHA256 != "" && o.SHA256 != "" {
+		return e.SHA256 == o.SHA256
+	}
+
+	return e.Inode != 0 && e.sameFile(o)
+}
+
+func sortSnapshotEntries(entries []SnapshotEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+}
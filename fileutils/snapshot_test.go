@@ -0,0 +1,139 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func snapshotPaths(entries []SnapshotEntry) []string {
+	var paths []string
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+	}
+
+	return paths
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	for _, hash := range []bool{false, true} {
+		work := testTree(t, fstest.MapFS{
+			"keep.txt":        {Data: []byte("keep"), Mode: 0600, ModTime: old},
+			"modify.txt":      {Data: []byte("modify"), Mode: 0600, ModTime: old},
+			"delete.txt":      {Data: []byte("delete"), Mode: 0600, ModTime: old},
+			"rename.txt":      {Data: []byte("rename"), Mode: 0600, ModTime: old},
+			"sub/move.txt":    {Data: []byte("move"), Mode: 0600, ModTime: old},
+			"sub/chmod.txt":   {Data: []byte("chmod"), Mode: 0600, ModTime: old},
+			"sub/deep/x.txt":  {Data: []byte("x"), Mode: 0600, ModTime: old},
+			"gone/inside.txt": {Data: []byte("inside"), Mode: 0600, ModTime: old},
+		})
+
+		before, err := TakeSnapshot(work, &SnapshotOptions{Hash: hash})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		snapFile := filepath.Join(t.TempDir(), "snapshot.json")
+		if err := before.Save(snapFile); err != nil {
+			t.Fatal(err)
+		}
+		before, err = LoadSnapshot(snapFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(work, "modify.txt"), []byte("modified"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove(filepath.Join(work, "delete.txt")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(work, "rename.txt"), filepath.Join(work, "renamed.txt")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(work, "sub", "move.txt"), filepath.Join(work, "moved.txt")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(filepath.Join(work, "sub", "chmod.txt"), 0640); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(work, "sub", "deep", "new.txt"), []byte("new"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.RemoveAll(filepath.Join(work, "gone")); err != nil {
+			t.Fatal(err)
+		}
+
+		after, err := TakeSnapshot(work, &SnapshotOptions{Hash: hash, Previous: before})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		diff := before.Diff(after)
+
+		if expected := []string{"sub/deep/new.txt"}; !reflect.DeepEqual(expected, snapshotPaths(diff.Created)) {
+			t.Errorf("hash %v: expected created %v, got %v", hash, expected, snapshotPaths(diff.Created))
+		}
+		if expected := []string{"modify.txt", "sub/chmod.txt"}; !reflect.DeepEqual(expected, snapshotPaths(diff.Modified)) {
+			t.Errorf("hash %v: expected modified %v, got %v", hash, expected, snapshotPaths(diff.Modified))
+		}
+		if expected := []string{"delete.txt", "gone", "gone/inside.txt"}; !reflect.DeepEqual(expected, snapshotPaths(diff.Deleted)) {
+			t.Errorf("hash %v: expected deleted %v, got %v", hash, expected, snapshotPaths(diff.Deleted))
+		}
+
+		var renames []string
+		for _, r := range diff.Renamed {
+			renames = append(renames, r.From.Path+" -> "+r.To.Path)
+		}
+		if expected := []string{"sub/move.txt -> moved.txt", "rename.txt -> renamed.txt"}; !reflect.DeepEqual(expected, renames) {
+			t.Errorf("hash %v: expected renamed %v, got %v", hash, expected, renames)
+		}
+
+		again, err := TakeSnapshot(work, &SnapshotOptions{Hash: hash})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := after.Diff(again); !diff.Empty() {
+			t.Errorf("hash %v: expected no changes, got %+v", hash, diff)
+		}
+	}
+}
+
+func TestSnapshotHashReuse(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "file.txt")
+
+	err := os.WriteFile(fileName, []byte("content"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := TakeSnapshot(dir, &SnapshotOptions{Hash: true, Include: []string{"*.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := FileHash(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before.Entries) != 1 || before.Entries[0].SHA256 != expected {
+		t.Fatalf("expected one hashed entry, got %+v", before.Entries)
+	}
+
+	// an unchanged file takes its hash from the previous snapshot
+	before.Entries[0].SHA256 = "cached"
+
+	after, err := TakeSnapshot(dir, &SnapshotOptions{Hash: true, Previous: before})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Entries[0].SHA256 != "cached" {
+		t.Errorf("expected the previous hash to be reused, got %v", after.Entries[0].SHA256)
+	}
+}
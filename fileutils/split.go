@@ -0,0 +1,287 @@
+package fileutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const splitManifestVersion = 1
+
+type SplitOptions struct {
+	// size of each part in bytes, the last part may be smaller
+	PartSize int64
+	// split into exactly this many parts, differing in size by at most a
+	// byte, used when PartSize is not set
+	Parts int
+	// folder for the parts and manifest, defaults to the folder of the file
+	Dir string
+	// units for the human readable sizes in the manifest, as accepted by
+	// ByteSizeConvert, e.g. "mb"
+	Units string
+}
+
+type SplitPart struct {
+	// file name relative to the manifest
+	Name      string `json:"name"`
+	Offset    int64  `json:"offset"`
+	Size      int64  `json:"size"`
+	HumanSize string `json:"human_size"`
+	SHA256    string `json:"sha256"`
+}
+
+// SplitManifest describes a split file. Digests are in the FileHash format.
+type SplitManifest struct {
+	Version   int         `json:"version"`
+	Name      string      `json:"name"`
+	Size      int64       `json:"size"`
+	HumanSize string      `json:"human_size"`
+	SHA256    string      `json:"sha256"`
+	Parts     []SplitPart `json:"parts"`
+}
+
+// SplitFile writes fileName out as numbered parts, fileName.part001 onwards,
+// along with a fileName.manifest.json that JoinFile uses to put it back
+// together. It returns the path of the manifest.
+func SplitFile(fileName string, opts *SplitOptions) (string, error) {
+	var funcName string = "SplitFile"
+
+	if opts == nil || (opts.PartSize <= 0 && opts.Parts <= 0) {
+		return "", newError(funcName, fileName, "part size or count required", ErrInvalid)
+	}
+
+	in, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return "", newError(funcName, fileName, "error opening file", err)
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return "", newError(funcName, fileName, "error getting file info", err)
+	}
+
+	if !fi.Mode().IsRegular() {
+		return "", newError(funcName, fileName, "", ErrNotRegular)
+	}
+
+	digest, err := FileHash(fileName)
+	if err != nil {
+		return "", newError(funcName, fileName, "error hashing file", err)
+	}
+
+	dir := opts.Dir
+	if dir == "" {
+		dir = filepath.Dir(fileName)
+	}
+	if err := MkDir(dir); err != nil {
+		return "", newError(funcName, dir, "error creating folder", err)
+	}
+
+	size := fi.Size()
+
+	base := filepath.Base(fileName)
+	manifest := SplitManifest{
+		Version:   splitManifestVersion,
+		Name:      base,
+		Size:      size,
+		HumanSize: byteSizeString(size, opts.Units),
+		SHA256:    digest,
+	}
+
+	var offset int64
+	for _, n := range splitSizes(size, opts) {
+		part := SplitPart{
+			Name:      fmt.Sprintf("%v.part%03d", base, len(manifest.Parts)+1),
+			Offset:    offset,
+			Size:      n,
+			HumanSize: byteSizeString(n, opts.Units),
+		}
+		partFile := filepath.Join(dir, part.Name)
+
+		err := writeFileAtomic(partFile, dataFilePerm, func(w io.Writer) error {
+			_, err := io.Copy(w, io.NewSectionReader(in, offset, n))
+			return err
+		})
+		if err != nil {
+			return "", newTargetError(funcName, fileName, partFile, "error writing part", err)
+		}
+
+		part.SHA256, err = FileHash(partFile)
+		if err != nil {
+			return "", newError(funcName, partFile, "error hashing part", err)
+		}
+
+		manifest.Parts = append(manifest.Parts, part)
+		offset += n
+	}
+
+	manifestFile := filepath.Join(dir, base+".manifest.json")
+	if err := WriteJSON(manifestFile, manifest, true); err != nil {
+		return "", newError(funcName, manifestFile, "error writing manifest", err)
+	}
+
+	return manifestFile, nil
+}
+
+// splitSizes returns the size of each part. With Parts the first size%Parts
+// parts are a byte larger than the rest, so there are always exactly Parts of
+// them. An empty file still gets one part, so joining recreates it.
+func splitSizes(size int64, opts *SplitOptions) []int64 {
+	if opts.PartSize <= 0 {
+		parts := int64(opts.Parts)
+
+		sizes := make([]int64, parts)
+		for i := range sizes {
+			sizes[i] = size / parts
+			if int64(i) < size%parts {
+				sizes[i]++
+			}
+		}
+
+		return sizes
+	}
+
+	var sizes []int64
+	for offset := int64(0); offset < size || len(sizes) == 0; offset += opts.PartSize {
+		n := opts.PartSize
+		if offset+n > size {
+			n = size - offset
+		}
+		sizes = append(sizes, n)
+	}
+
+	return sizes
+}
+
+func ReadSplitManifest(manifestFile string) (SplitManifest, error) {
+	var funcName string = "ReadSplitManifest"
+
+	manifest, err := ReadJSON[SplitManifest](manifestFile)
+	if err != nil {
+		return manifest, newError(funcName, manifestFile, "error reading manifest", err)
+	}
+
+	if manifest.Version != splitManifestVersion {
+		return manifest, newTargetError(funcName, manifestFile, fmt.Sprint(manifest.Version), "unsupported manifest version", ErrInvalid)
+	}
+
+	return manifest, nil
+}
+
+// VerifySplit checks the size and digest of every part listed in the
+// manifest. A part that does not match is reported with ErrChecksum.
+func VerifySplit(manifestFile string) error {
+	var funcName string = "VerifySplit"
+
+	manifest, err := ReadSplitManifest(manifestFile)
+	if err != nil {
+		return err
+	}
+
+	for _, part := range manifest.Parts {
+		if err := verifySplitPart(manifestFile, part); err != nil {
+			return newError(funcName, manifestFile, "error verifying part", err)
+		}
+	}
+
+	return nil
+}
+
+// JoinFile verifies every part listed in the manifest and reassembles them
+// into dst, which is only replaced once the joined content matches the
+// digest of the original file.
+func JoinFile(manifestFile, dst string) error {
+	var funcName string = "JoinFile"
+
+	manifest, err := ReadSplitManifest(manifestFile)
+	if err != nil {
+		return err
+	}
+
+	var parts []string
+	var offset int64
+	for _, part := range manifest.Parts {
+		if part.Offset != offset {
+			return newTargetError(funcName, manifestFile, part.Name, "parts are not contiguous", ErrInvalid)
+		}
+		offset += part.Size
+
+		if err := verifySplitPart(manifestFile, part); err != nil {
+			return newError(funcName, manifestFile, "error verifying part", err)
+		}
+
+		partFile, _ := SecureJoin(filepath.Dir(manifestFile), part.Name)
+		parts = append(parts, partFile)
+	}
+
+	if offset != manifest.Size {
+		return newTargetError(funcName, manifestFile, fmt.Sprint(offset), "parts do not add up to file size", ErrInvalid)
+	}
+
+	err = writeFileAtomic(dst, dataFilePerm, func(w io.Writer) error {
+		h := sha256.New()
+		w = io.MultiWriter(w, h)
+
+		for _, partFile := range parts {
+			if err := appendFile(w, partFile); err != nil {
+				return err
+			}
+		}
+
+		// the parts may have changed since they were verified
+		if digest := hex.EncodeToString(h.Sum(nil)); digest != manifest.SHA256 {
+			return newTargetError(funcName, dst, digest, "", ErrChecksum)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return newTargetError(funcName, manifestFile, dst, "error joining file", err)
+	}
+
+	return nil
+}
+
+func verifySplitPart(manifestFile string, part SplitPart) error {
+	var funcName string = "verifySplitPart"
+
+	// part names come from the manifest and must stay next to it
+	partFile, err := SecureJoin(filepath.Dir(manifestFile), part.Name)
+	if err != nil {
+		return err
+	}
+
+	size, err := FileSizeBytes(partFile)
+	if err != nil {
+		return err
+	}
+	if size != part.Size {
+		return newTargetError(funcName, partFile, byteSizeString(size, ""), "unexpected size", ErrChecksum)
+	}
+
+	digest, err := FileHash(partFile)
+	if err != nil {
+		return err
+	}
+	if digest != part.SHA256 {
+		return newTargetError(funcName, partFile, digest, "", ErrChecksum)
+	}
+
+	return nil
+}
+
+func appendFile(w io.Writer, fileName string) error {
+	f, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+
+	return err
+}
@@ -0,0 +1,169 @@
+package fileutils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitFile(t *testing.T) {
+	dir := t.TempDir()
+
+	content := make([]byte, 10*1024+5)
+	if _, err := rand.Read(content); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		content []byte
+		opts    *SplitOptions
+		parts   int
+		last    int64
+	}{
+		"part size": {
+			content: content,
+			opts:    &SplitOptions{PartSize: 4096, Units: "kb"},
+			parts:   3,
+			last:    2053,
+		},
+		"part count": {
+			content: content,
+			opts:    &SplitOptions{Parts: 4},
+			parts:   4,
+			last:    2561,
+		},
+		"part count uneven": {
+			content: content[:9],
+			opts:    &SplitOptions{Parts: 4},
+			parts:   4,
+			last:    2,
+		},
+		"more parts than bytes": {
+			content: content[:2],
+			opts:    &SplitOptions{Parts: 4},
+			parts:   4,
+			last:    0,
+		},
+		"empty": {
+			content: []byte{},
+			opts:    &SplitOptions{PartSize: 4096},
+			parts:   1,
+			last:    0,
+		},
+	}
+
+	for name, tt := range tests {
+		src := filepath.Join(dir, name+".bin")
+		if err := os.WriteFile(src, tt.content, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		tt.opts.Dir = filepath.Join(dir, name)
+		manifestFile, err := SplitFile(src, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		manifest, err := ReadSplitManifest(manifestFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := FileHash(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if manifest.SHA256 != expected {
+			t.Errorf("%s: expected digest %v, got %v", name, expected, manifest.SHA256)
+		}
+
+		if len(manifest.Parts) != tt.parts {
+			t.Fatalf("%s: expected %d parts, got %d", name, tt.parts, len(manifest.Parts))
+		}
+
+		partFiles, err := filepath.Glob(filepath.Join(tt.opts.Dir, "*.part*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(partFiles) != tt.parts {
+			t.Errorf("%s: expected %d part files, got %v", name, tt.parts, partFiles)
+		}
+		if last := manifest.Parts[len(manifest.Parts)-1].Size; last != tt.last {
+			t.Errorf("%s: expected last part of %d bytes, got %d", name, tt.last, last)
+		}
+
+		if err := VerifySplit(manifestFile); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+
+		dst := filepath.Join(dir, name+".joined")
+		if err := JoinFile(manifestFile, dst); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		joined, err := os.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(tt.content, joined) {
+			t.Errorf("%s: expected joined file to match", name)
+		}
+	}
+
+	manifest, err := ReadSplitManifest(filepath.Join(dir, "part size", "part size.bin.manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.HumanSize != "10.00kb" || manifest.Parts[0].HumanSize != "4.00kb" {
+		t.Errorf("expected sizes in kb, got %v and %v", manifest.HumanSize, manifest.Parts[0].HumanSize)
+	}
+
+	if _, err := SplitFile(filepath.Join(dir, "empty.bin"), nil); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid without a part size, got %v", err)
+	}
+}
+
+func TestJoinFileCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	dst := filepath.Join(dir, "dst.txt")
+
+	err := os.WriteFile(src, []byte("0123456789"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifestFile, err := SplitFile(src, &SplitOptions{PartSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "src.txt.part002"), []byte("4x67"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifySplit(manifestFile); !errors.Is(err, ErrChecksum) {
+		t.Errorf("expected ErrChecksum verifying, got %v", err)
+	}
+
+	if err := JoinFile(manifestFile, dst); !errors.Is(err, ErrChecksum) {
+		t.Errorf("expected ErrChecksum joining, got %v", err)
+	}
+
+	if FileExists(dst) {
+		t.Errorf("expected no output from a failed join")
+	}
+
+	err = os.Remove(filepath.Join(dir, "src.txt.part001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifySplit(manifestFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected not exist error for a missing part, got %v", err)
+	}
+}
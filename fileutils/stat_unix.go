@@ -0,0 +1,41 @@
+//go:build !windows
+
+package fileutils
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileInode returns the inode number of fi, or 0 when it is not known.
+func fileInode(fi os.FileInfo) uint64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino) //nolint:unconvert
+	}
+
+	return 0
+}
+
+// fileOwner returns the uid owning fi, ok is false when it is not known.
+func fileOwner(fi os.FileInfo) (uid int, ok bool) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid), true
+	}
+
+	return 0, false
+}
+
+// fileDevice returns the id of the device holding p.
+func fileDevice(p string) (uint64, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return 0, err
+	}
+
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+
+	return uint64(st.Dev), nil //nolint:unconvert
+}
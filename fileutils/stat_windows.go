@@ -0,0 +1,23 @@
+package fileutils
+
+import (
+	"os"
+)
+
+// fileInode returns 0, windows has file indexes but os.FileInfo does not
+// expose them.
+func fileInode(fi os.FileInfo) uint64 {
+	return 0
+}
+
+// fileOwner never knows the owner, windows files have security descriptors
+// rather than uids.
+func fileOwner(fi os.FileInfo) (uid int, ok bool) {
+	return 0, false
+}
+
+// fileDevice is not supported on windows, where there is no trash to find a
+// volume for.
+func fileDevice(p string) (uint64, error) {
+	return 0, ErrUnsupported
+}
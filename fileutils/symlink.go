@@ -0,0 +1,173 @@
+package fileutils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rockwell-uk/go-utils/stringutils"
+)
+
+// maximum number of links followed before giving up, matches the linux limit.
+const maxSymlinkHops = 40
+
+type SymlinkReport struct {
+	Dangling []string
+	Outside  []string
+}
+
+// ResolveSymlinkChain follows fileName one link at a time and returns every
+// path visited, starting with fileName itself and ending with the first path
+// that is not a symlink. The final path does not have to exist.
+func ResolveSymlinkChain(fileName string) ([]string, error) {
+	var funcName string = "ResolveSymlinkChain"
+
+	current := filepath.Clean(fileName)
+	chain := []string{current}
+	seen := map[string]bool{current: true}
+
+	for {
+		fi, err := os.Lstat(current)
+		if os.IsNotExist(err) && len(chain) > 1 {
+			// dangling link, report how far we got
+			return chain, nil
+		}
+		if err != nil {
+			return chain, newError(funcName, current, "error checking file info", err)
+		}
+
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return chain, nil
+		}
+
+		if len(chain) > maxSymlinkHops {
+			return chain, newError(funcName, fileName, "", ErrSymlinkLoop)
+		}
+
+		dest, err := os.Readlink(current)
+		if err != nil {
+			return chain, newError(funcName, current, "error reading link", err)
+		}
+
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(filepath.Dir(current), dest)
+		}
+		dest = filepath.Clean(dest)
+
+		chain = append(chain, dest)
+		if seen[dest] {
+			return chain, newError(funcName, strings.Join(chain, " -> "), "symlink loop detected", ErrSymlinkLoop)
+		}
+		seen[dest] = true
+
+		current = dest
+	}
+}
+
+// RelativeSymlink creates linkName pointing at target using a path relative
+// to the folder linkName lives in. Both paths must be absolute.
+func RelativeSymlink(target, linkName string) error {
+	var funcName string = "RelativeSymlink"
+
+	if !filepath.IsAbs(target) || !filepath.IsAbs(linkName) {
+		return newTargetError(funcName, target, linkName, "", ErrNotAbsolute)
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(linkName), target)
+	if err != nil {
+		return newTargetError(funcName, target, linkName, "error making relative path", err)
+	}
+
+	if err := os.Symlink(rel, linkName); err != nil {
+		return newError(funcName, linkName, "error creating link", err)
+	}
+
+	return nil
+}
+
+// ReplaceSymlink atomically points linkName at target. A new link is created
+// alongside linkName and renamed over it, so readers always see either the old
+// or the new target. Existing folders are never replaced.
+func ReplaceSymlink(target, linkName string) error {
+	var funcName string = "ReplaceSymlink"
+
+	if fi, err := os.Lstat(linkName); err == nil && fi.Mode()&os.ModeSymlink == 0 {
+		return newError(funcName, linkName, "", ErrNotSymlink)
+	}
+
+	tmp := filepath.Join(filepath.Dir(linkName), fmt.Sprintf(".%v.%v.tmp", filepath.Base(linkName), stringutils.RandString(8)))
+
+	if err := os.Symlink(target, tmp); err != nil {
+		return newError(funcName, tmp, "error creating link", err)
+	}
+
+	if err := os.Rename(tmp, linkName); err != nil {
+		os.Remove(tmp)
+		return newError(funcName, linkName, "error replacing link", err)
+	}
+
+	return nil
+}
+
+// ScanSymlinks walks folderPath and reports links whose chain ends at a path
+// that does not exist, and links that resolve to somewhere outside folderPath.
+func ScanSymlinks(folderPath string) (SymlinkReport, error) {
+	var funcName string = "ScanSymlinks"
+
+	var report SymlinkReport
+
+	if !IsFolder(folderPath) {
+		return report, newError(funcName, folderPath, "", ErrNotFolder)
+	}
+
+	root, err := filepath.EvalSymlinks(folderPath)
+	if err != nil {
+		return report, newError(funcName, folderPath, "error resolving target", err)
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return report, newError(funcName, folderPath, "error resolving target", err)
+	}
+
+	err = filepath.WalkDir(folderPath, func(s string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+		if d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+
+		resolved, err := filepath.EvalSymlinks(s)
+		if err != nil {
+			report.Dangling = append(report.Dangling, s)
+			return nil //nolint:nilerr
+		}
+		resolved, err = filepath.Abs(resolved)
+		if err != nil {
+			return err
+		}
+
+		if !pathWithin(root, resolved) {
+			report.Outside = append(report.Outside, s)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return report, newError(funcName, folderPath, "error walking target", err)
+	}
+
+	return report, nil
+}
+
+func pathWithin(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
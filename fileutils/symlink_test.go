@@ -0,0 +1,203 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveSymlinkChain(t *testing.T) {
+	dir := t.TempDir()
+
+	err := MkFile(filepath.Join(dir, "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := map[string]string{
+		"a":     "b",
+		"b":     filepath.Join(dir, "c"),
+		"c":     "file.txt",
+		"loop1": "loop2",
+		"loop2": "loop1",
+		"dead":  "missing.txt",
+	}
+	for name, target := range links {
+		err := os.Symlink(target, filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]struct {
+		path        string
+		expected    []string
+		shouldError bool
+	}{
+		"chain": {
+			path: filepath.Join(dir, "a"),
+			expected: []string{
+				filepath.Join(dir, "a"),
+				filepath.Join(dir, "b"),
+				filepath.Join(dir, "c"),
+				filepath.Join(dir, "file.txt"),
+			},
+		},
+		"regular file": {
+			path:     filepath.Join(dir, "file.txt"),
+			expected: []string{filepath.Join(dir, "file.txt")},
+		},
+		"dangling": {
+			path: filepath.Join(dir, "dead"),
+			expected: []string{
+				filepath.Join(dir, "dead"),
+				filepath.Join(dir, "missing.txt"),
+			},
+		},
+		"loop": {
+			path:        filepath.Join(dir, "loop1"),
+			shouldError: true,
+		},
+		"missing path": {
+			path:        filepath.Join(dir, "nofile.txt"),
+			shouldError: true,
+		},
+	}
+
+	for name, tt := range tests {
+		actual, err := ResolveSymlinkChain(tt.path)
+
+		if err == nil && tt.shouldError {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+		if err != nil && !tt.shouldError {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+
+		if tt.expected != nil && !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%s: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestRelativeSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "releases", "1")
+	linkName := filepath.Join(dir, "current")
+
+	err := MkDir(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = RelativeSymlink(target, linkName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := os.Readlink(linkName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dest != filepath.Join("releases", "1") {
+		t.Errorf("expected relative link, got %v", dest)
+	}
+
+	err = RelativeSymlink("releases/1", linkName)
+	if err == nil {
+		t.Errorf("expected error for relative target, got nil")
+	}
+}
+
+func TestReplaceSymlink(t *testing.T) {
+	dir := t.TempDir()
+	linkName := filepath.Join(dir, "current")
+
+	for _, release := range []string{"releases/1", "releases/2"} {
+		err := MkDir(filepath.Join(dir, release))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ReplaceSymlink(release, linkName)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dest, err := os.Readlink(linkName)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if dest != release {
+			t.Errorf("expected link to point to %v, got %v", release, dest)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Errorf("expected temporary links to be cleaned up, got %v entries", len(entries))
+	}
+
+	err = ReplaceSymlink("releases/1", filepath.Join(dir, "releases"))
+	if err == nil {
+		t.Errorf("expected error replacing a folder, got nil")
+	}
+}
+
+func TestScanSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+
+	err := MkDir(filepath.Join(root, "sub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = MkFile(filepath.Join(root, "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = MkFile(filepath.Join(dir, "outside.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := map[string]string{
+		"sub/ok":      "../file.txt",
+		"sub/dead":    "nothing",
+		"escape":      "../outside.txt",
+		"escape-abs":  filepath.Join(dir, "outside.txt"),
+		"folder-link": "sub",
+	}
+	for name, target := range links {
+		err := os.Symlink(target, filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := ScanSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := SymlinkReport{
+		Dangling: []string{filepath.Join(root, "sub/dead")},
+		Outside: []string{
+			filepath.Join(root, "escape"),
+			filepath.Join(root, "escape-abs"),
+		},
+	}
+
+	if !reflect.DeepEqual(expected, report) {
+		t.Errorf("expected %v, got %v", expected, report)
+	}
+}
@@ -0,0 +1,457 @@
+package fileutils
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	trashInfoExt    = ".trashinfo"
+	trashInfoHeader = "[Trash Info]"
+	trashDateFormat = "2006-01-02T15:04:05"
+)
+
+// trashMounts lists the mount points whose trash folders ListTrash and
+// EmptyTrash look at besides the home trash, replaced in tests.
+var trashMounts = mountPoints
+
+// TrashItem is a file or folder in a trash folder.
+type TrashItem struct {
+	// name of the item inside the trash, unique within it
+	Name string
+	// absolute path the item was trashed from
+	Path string
+	// when it was trashed, to the second in local time
+	Deleted time.Time
+	// trash folder holding the item, with files and info inside
+	Trash string
+}
+
+// File returns where the trashed item currently is.
+func (i TrashItem) File() string {
+	return filepath.Join(i.Trash, "files", i.Name)
+}
+
+func (i TrashItem) infoFile() string {
+	return filepath.Join(i.Trash, "info", i.Name+trashInfoExt)
+}
+
+// trashDir is a trash folder and the top of the volume it belongs to. Paths in
+// the info files of a volume trash are relative to top, the home trash has no
+// top and stores absolute paths.
+type trashDir struct {
+	dir string
+	top string
+}
+
+// Trash moves fileName into the trash following the freedesktop.org Trash
+// specification, so it can be listed and restored later. Files on the same
+// volume as $XDG_DATA_HOME go to its Trash folder, files on other volumes go
+// to .Trash/$uid or .Trash-$uid at the top of their volume. Symlinks are
+// trashed themselves, not their targets.
+func Trash(fileName string) (TrashItem, error) {
+	var funcName string = "Trash"
+
+	p, err := filepath.Abs(fileName)
+	if err != nil {
+		return TrashItem{}, newError(funcName, fileName, "error resolving path", err)
+	}
+
+	if _, err := os.Lstat(p); err != nil {
+		return TrashItem{}, newError(funcName, p, "error checking file info", err)
+	}
+
+	t, err := trashDirFor(p)
+	if err != nil {
+		return TrashItem{}, newError(funcName, p, "error finding trash", err)
+	}
+
+	item, err := trashTo(p, t)
+	if err != nil {
+		return TrashItem{}, newTargetError(funcName, p, t.dir, "error moving to trash", err)
+	}
+
+	return item, nil
+}
+
+// ListTrash returns the items in the home trash and in the trash folders of
+// mounted volumes, oldest first. Info files that cannot be parsed, and those
+// whose file has gone, are skipped.
+func ListTrash() ([]TrashItem, error) {
+	var funcName string = "ListTrash"
+
+	dirs, err := trashDirs()
+	if err != nil {
+		return nil, newError(funcName, "", "error finding trash", err)
+	}
+
+	var items []TrashItem
+	for _, t := range dirs {
+		found, err := readTrash(t)
+		if err != nil {
+			return nil, newError(funcName, t.dir, "error reading trash", err)
+		}
+		items = append(items, found...)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Deleted.Before(items[j].Deleted)
+	})
+
+	return items, nil
+}
+
+// RestoreFromTrash moves item back to where it was trashed from, recreating
+// missing parent folders. Nothing is overwritten, an error matching ErrExist
+// is returned when something is already there.
+func RestoreFromTrash(item TrashItem) error {
+	var funcName string = "RestoreFromTrash"
+
+	fi, err := os.Lstat(item.File())
+	if err != nil {
+		return newError(funcName, item.File(), "error checking file info", err)
+	}
+
+	if err := MkDir(filepath.Dir(item.Path)); err != nil {
+		return err
+	}
+
+	if err := renameNoReplace(item.File(), item.Path, fi.IsDir()); err != nil {
+		if os.IsExist(err) {
+			return newError(funcName, item.Path, "", ErrExist)
+		}
+		return newTargetError(funcName, item.File(), item.Path, "error restoring file", err)
+	}
+
+	if err := os.Remove(item.infoFile()); err != nil && !os.IsNotExist(err) {
+		return newError(funcName, item.infoFile(), "error removing file", err)
+	}
+
+	return nil
+}
+
+// EmptyTrash permanently removes everything in the home trash and in the
+// trash folders of mounted volumes. Like EmptyFolder it carries on past
+// failures and returns the first one.
+func EmptyTrash() error {
+	var funcName string = "EmptyTrash"
+
+	dirs, err := trashDirs()
+	if err != nil {
+		return newError(funcName, "", "error finding trash", err)
+	}
+
+	var firstErr error
+	for _, t := range dirs {
+		// files first, an info file without its file is harmless
+		for _, sub := range []string{"files", "info"} {
+			dir := filepath.Join(t.dir, sub)
+			if !FolderExists(dir) {
+				continue
+			}
+			if err := EmptyFolder(dir); err != nil && firstErr == nil {
+				firstErr = newError(funcName, dir, "error emptying folder", err)
+			}
+		}
+	}
+
+	return firstErr
+}
+
+func homeTrash() (trashDir, error) {
+	data, err := XDGDataHome()
+	if err != nil {
+		return trashDir{}, err
+	}
+
+	return trashDir{dir: filepath.Join(data, "Trash")}, nil
+}
+
+// trashDirs returns the home trash and the trash folders of mounted volumes
+// that exist.
+func trashDirs() ([]trashDir, error) {
+	home, err := homeTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []trashDir{home}
+	for _, top := range trashMounts() {
+		for _, t := range volumeTrashes(top) {
+			if IsFolder(t.dir) && t.dir != home.dir {
+				dirs = append(dirs, t)
+			}
+		}
+	}
+
+	return dirs, nil
+}
+
+// volumeTrashes returns the two trash folders the spec allows at the top of a
+// volume, $top/.Trash/$uid and $top/.Trash-$uid.
+func volumeTrashes(top string) []trashDir {
+	uid := strconv.Itoa(os.Getuid())
+
+	return []trashDir{
+		{dir: filepath.Join(top, ".Trash", uid), top: top},
+		{dir: filepath.Join(top, ".Trash-"+uid), top: top},
+	}
+}
+
+// trashDirFor picks the trash folder for the absolute path p, the home trash
+// when p is on the same volume and otherwise one at the top of p's volume.
+func trashDirFor(p string) (trashDir, error) {
+	home, err := homeTrash()
+	if err != nil {
+		return trashDir{}, err
+	}
+
+	dev, err := fileDevice(filepath.Dir(p))
+	if err != nil {
+		return trashDir{}, err
+	}
+
+	homeDev, err := fileDevice(existingParent(home.dir))
+	if err != nil {
+		return trashDir{}, err
+	}
+
+	if dev == homeDev {
+		return home, nil
+	}
+
+	top, err := volumeTop(filepath.Dir(p), dev)
+	if err != nil {
+		return trashDir{}, err
+	}
+
+	return volumeTrash(top)
+}
+
+// volumeTrash uses $top/.Trash/$uid when an administrator has set up a
+// shared .Trash folder, which must be a real folder with the sticky bit set,
+// and $top/.Trash-$uid otherwise.
+func volumeTrash(top string) (trashDir, error) {
+	trashes := volumeTrashes(top)
+
+	fi, err := os.Lstat(filepath.Join(top, ".Trash"))
+	if err == nil && fi.IsDir() && fi.Mode()&os.ModeSticky != 0 {
+		if err := MkDirMode(trashes[0].dir, 0700); err == nil {
+			return trashes[0], nil
+		}
+	}
+
+	return trashes[1], nil
+}
+
+// volumeTop walks up from dir, which is on device dev, to the highest folder
+// still on the same device.
+func volumeTop(dir string, dev uint64) (string, error) {
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+
+		d, err := fileDevice(parent)
+		if err != nil {
+			return "", err
+		}
+		if d != dev {
+			return dir, nil
+		}
+
+		dir = parent
+	}
+}
+
+func existingParent(p string) string {
+	for {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+
+		parent := filepath.Dir(p)
+		if parent == p {
+			return p
+		}
+		p = parent
+	}
+}
+
+// trashTo moves the absolute path p into t. The info file is created first,
+// exclusively, which is what reserves the name.
+func trashTo(p string, t trashDir) (TrashItem, error) {
+	files := filepath.Join(t.dir, "files")
+	info := filepath.Join(t.dir, "info")
+
+	for _, dir := range []string{files, info} {
+		if err := MkDirMode(dir, 0700); err != nil {
+			return TrashItem{}, err
+		}
+	}
+
+	stored := p
+	if t.top != "" {
+		rel, err := filepath.Rel(t.top, p)
+		if err != nil {
+			return TrashItem{}, err
+		}
+		stored = rel
+	}
+
+	item := TrashItem{
+		Path:    p,
+		Deleted: time.Now().Truncate(time.Second),
+		Trash:   t.dir,
+	}
+
+	base := filepath.Base(p)
+	for n := 1; ; n++ {
+		item.Name = base
+		if n > 1 {
+			item.Name = fmt.Sprintf("%s.%d", base, n)
+		}
+
+		f, err := os.OpenFile(item.infoFile(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return TrashItem{}, err
+		}
+
+		// a file left behind without its info file also holds the name
+		if _, err := os.Lstat(item.File()); err == nil {
+			f.Close()
+			os.Remove(item.infoFile())
+			continue
+		}
+
+		_, err = fmt.Fprintf(f, "%s\nPath=%s\nDeletionDate=%s\n", trashInfoHeader, escapeTrashPath(stored), item.Deleted.Format(trashDateFormat))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(item.infoFile())
+			return TrashItem{}, err
+		}
+
+		break
+	}
+
+	if err := os.Rename(p, item.File()); err != nil {
+		os.Remove(item.infoFile())
+		return TrashItem{}, err
+	}
+
+	return item, nil
+}
+
+func readTrash(t trashDir) ([]TrashItem, error) {
+	info := filepath.Join(t.dir, "info")
+
+	entries, err := os.ReadDir(info)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []TrashItem
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), trashInfoExt)
+		if name == e.Name() || !e.Type().IsRegular() {
+			continue
+		}
+
+		item, err := readTrashInfo(filepath.Join(info, e.Name()))
+		if err != nil {
+			continue
+		}
+
+		item.Name = name
+		item.Trash = t.dir
+		if !filepath.IsAbs(item.Path) {
+			if t.top == "" {
+				continue
+			}
+			item.Path = filepath.Join(t.top, item.Path)
+		}
+
+		if _, err := os.Lstat(item.File()); err != nil {
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func readTrashInfo(fileName string) (TrashItem, error) {
+	var item TrashItem
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return item, err
+	}
+	defer f.Close()
+
+	var inGroup bool
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inGroup = line == trashInfoHeader
+			continue
+		}
+		if !inGroup {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "Path":
+			p, err := url.PathUnescape(value)
+			if err != nil {
+				return item, err
+			}
+			item.Path = filepath.FromSlash(p)
+		case "DeletionDate":
+			d, err := time.ParseInLocation(trashDateFormat, value, time.Local)
+			if err != nil {
+				return item, err
+			}
+			item.Deleted = d
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return item, err
+	}
+
+	if item.Path == "" {
+		return item, ErrInvalid
+	}
+
+	return item, nil
+}
+
+// escapeTrashPath percent encodes p as the spec asks, leaving slashes alone.
+func escapeTrashPath(p string) string {
+	u := url.URL{Path: filepath.ToSlash(p)}
+
+	return u.EscapedPath()
+}
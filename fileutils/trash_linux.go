@@ -0,0 +1,109 @@
+package fileutils
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// AT_FDCWD from fcntl.h
+	atFdCwd = -0x64
+	// RENAME_NOREPLACE from linux/fs.h
+	renameNoReplaceFlag = 0x1
+)
+
+// renameat2 is missing from the syscall package on most architectures
+var sysRenameat2 = map[string]uintptr{
+	"386":      353,
+	"amd64":    316,
+	"arm":      382,
+	"arm64":    276,
+	"loong64":  276,
+	"mips":     4351,
+	"mipsle":   4351,
+	"mips64":   5311,
+	"mips64le": 5311,
+	"ppc64":    357,
+	"ppc64le":  357,
+	"riscv64":  276,
+	"s390x":    347,
+}
+
+// mountPoints lists the mount points in /proc/self/mounts, or nothing when it
+// cannot be read.
+func mountPoints() []string {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var mounts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		mounts = append(mounts, unescapeMount(fields[1]))
+	}
+
+	return mounts
+}
+
+// unescapeMount decodes the octal escapes the kernel uses for spaces, tabs,
+// newlines and backslashes in mount points.
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// renameat2 renames src to dst with RENAME_NOREPLACE, so the kernel fails
+// with EEXIST rather than replacing dst. It returns ErrUnsupported when the
+// kernel, or the filesystem, does not know the flag.
+func renameat2(src, dst string) error {
+	nr, ok := sysRenameat2[runtime.GOARCH]
+	if !ok {
+		return ErrUnsupported
+	}
+
+	oldp, err := syscall.BytePtrFromString(src)
+	if err != nil {
+		return err
+	}
+	newp, err := syscall.BytePtrFromString(dst)
+	if err != nil {
+		return err
+	}
+
+	cwd := atFdCwd
+	_, _, errno := syscall.Syscall6(nr, uintptr(cwd), uintptr(unsafe.Pointer(oldp)), uintptr(cwd), uintptr(unsafe.Pointer(newp)), renameNoReplaceFlag, 0)
+	switch errno {
+	case 0:
+		return nil
+	case syscall.ENOSYS, syscall.EINVAL:
+		return ErrUnsupported
+	}
+
+	return &os.LinkError{Op: "renameat2", Old: src, New: dst, Err: errno}
+}
@@ -0,0 +1,25 @@
+package fileutils
+
+import (
+	"testing"
+)
+
+func TestUnescapeMount(t *testing.T) {
+	tests := map[string]struct {
+		mount    string
+		expected string
+	}{
+		"plain":     {mount: "/media/usb", expected: "/media/usb"},
+		"space":     {mount: `/media/my\040disk`, expected: "/media/my disk"},
+		"backslash": {mount: `/mnt/a\134b`, expected: `/mnt/a\b`},
+		"tab":       {mount: `/mnt/a\011b`, expected: "/mnt/a\tb"},
+		"truncated": {mount: `/mnt/a\04`, expected: `/mnt/a\04`},
+	}
+
+	for name, tt := range tests {
+		actual := unescapeMount(tt.mount)
+		if actual != tt.expected {
+			t.Errorf("%s: expected %q, got %q", name, tt.expected, actual)
+		}
+	}
+}
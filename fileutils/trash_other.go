@@ -0,0 +1,14 @@
+//go:build !linux
+
+package fileutils
+
+// mountPoints is only implemented on linux, elsewhere just the home trash is
+// used for listing and emptying.
+func mountPoints() []string {
+	return nil
+}
+
+// renameat2 is linux only, renameNoReplace falls back to linking.
+func renameat2(src, dst string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,415 @@
+package fileutils
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTrash(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	// only the home trash, never the real ones on mounted volumes
+	saved := trashMounts
+	trashMounts = func() []string { return nil }
+	t.Cleanup(func() { trashMounts = saved })
+
+	tests := map[string]struct {
+		name   string
+		folder bool
+	}{
+		"file":            {name: "report.txt"},
+		"folder":          {name: "logs", folder: true},
+		"needs escaping":  {name: "100% done #1.txt"},
+		"unicode":         {name: "café.txt"},
+		"symlink dangles": {name: "link"},
+	}
+
+	for name, tt := range tests {
+		p := filepath.Join(dir, "work", tt.name)
+		if err := MkDir(filepath.Dir(p)); err != nil {
+			t.Fatal(err)
+		}
+
+		switch {
+		case tt.folder:
+			if err := MkDir(filepath.Join(p, "sub")); err != nil {
+				t.Fatal(err)
+			}
+		case tt.name == "link":
+			if err := os.Symlink("missing", p); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			if err := os.WriteFile(p, []byte(name), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		item, err := Trash(p)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if _, err := os.Lstat(p); !os.IsNotExist(err) {
+			t.Errorf("%s: expected original to be gone, got %v", name, err)
+		}
+		if _, err := os.Lstat(item.File()); err != nil {
+			t.Errorf("%s: expected trashed file, got %v", name, err)
+		}
+
+		expectedTrash := filepath.Join(dir, "data", "Trash")
+		if item.Trash != expectedTrash {
+			t.Errorf("%s: expected trash %v, got %v", name, expectedTrash, item.Trash)
+		}
+		if item.Path != p {
+			t.Errorf("%s: expected path %v, got %v", name, p, item.Path)
+		}
+
+		if err := RestoreFromTrash(item); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := os.Lstat(p); err != nil {
+			t.Errorf("%s: expected restored file, got %v", name, err)
+		}
+		if _, err := os.Lstat(item.infoFile()); !os.IsNotExist(err) {
+			t.Errorf("%s: expected info file to be gone, got %v", name, err)
+		}
+	}
+
+	if _, err := Trash(filepath.Join(dir, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing: expected %v, got %v", ErrNotExist, err)
+	}
+}
+
+func TestTrashInfo(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	saved := trashMounts
+	trashMounts = func() []string { return nil }
+	t.Cleanup(func() { trashMounts = saved })
+
+	p := filepath.Join(dir, "a dir", "50% off.txt")
+	if err := MkDir(filepath.Dir(p)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := Trash(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(item.infoFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	escaped := filepath.ToSlash(filepath.Join(dir, "a%20dir", "50%25%20off.txt"))
+	expected := "[Trash Info]\nPath=" + escaped + "\nDeletionDate=" + item.Deleted.Format("2006-01-02T15:04:05") + "\n"
+	if string(b) != expected {
+		t.Errorf("expected %q, got %q", expected, string(b))
+	}
+
+	fi, err := os.Stat(filepath.Join(item.Trash, "info"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0700 {
+		t.Errorf("expected mode %v, got %v", os.FileMode(0700), fi.Mode().Perm())
+	}
+}
+
+func TestListTrash(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	saved := trashMounts
+	trashMounts = func() []string { return nil }
+	t.Cleanup(func() { trashMounts = saved })
+
+	// the same name trashed three times gets three entries
+	p := filepath.Join(dir, "notes.txt")
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(p, []byte(strconv.Itoa(i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Trash(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	home := filepath.Join(dir, "data", "Trash")
+
+	// malformed and orphaned info files are skipped
+	junk := map[string]string{
+		"nopath.trashinfo":   "[Trash Info]\nDeletionDate=2020-01-01T00:00:00\n",
+		"badgroup.trashinfo": "[Other]\nPath=/x\n",
+		"orphan.trashinfo":   "[Trash Info]\nPath=/orphan\nDeletionDate=2020-01-01T00:00:00\n",
+		"relative.trashinfo": "[Trash Info]\nPath=relative\nDeletionDate=2020-01-01T00:00:00\n",
+		"README":             "not an info file",
+	}
+	for name, content := range junk {
+		if err := os.WriteFile(filepath.Join(home, "info", name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"nopath", "badgroup", "relative"} {
+		if err := os.WriteFile(filepath.Join(home, "files", name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, err := ListTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string]bool{}
+	for _, item := range items {
+		names[item.Name] = true
+		if item.Path != p {
+			t.Errorf("%s: expected path %v, got %v", item.Name, p, item.Path)
+		}
+	}
+
+	expected := map[string]bool{"notes.txt": true, "notes.txt.2": true, "notes.txt.3": true}
+	if len(names) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+	for name := range expected {
+		if !names[name] {
+			t.Errorf("expected %v in %v", name, names)
+		}
+	}
+
+	// restoring one when the original path is taken again fails
+	if err := os.WriteFile(p, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreFromTrash(items[0]); !errors.Is(err, ErrExist) {
+		t.Errorf("conflict: expected %v, got %v", ErrExist, err)
+	}
+}
+
+func TestRestoreFromTrashConflict(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	saved := trashMounts
+	trashMounts = func() []string { return nil }
+	t.Cleanup(func() { trashMounts = saved })
+
+	tests := map[string]struct {
+		folder bool
+	}{
+		"file":   {},
+		"folder": {folder: true},
+	}
+
+	for name, tt := range tests {
+		p := filepath.Join(dir, name)
+
+		if tt.folder {
+			if err := MkDir(filepath.Join(p, "sub")); err != nil {
+				t.Fatal(err)
+			}
+		} else if err := os.WriteFile(p, []byte("trashed"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		item, err := Trash(p)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// whatever took the path since, even an empty folder, is kept
+		if tt.folder {
+			err = os.Mkdir(p, 0755)
+		} else {
+			err = os.WriteFile(p, []byte("new"), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := RestoreFromTrash(item); !errors.Is(err, ErrExist) {
+			t.Errorf("%s: expected %v, got %v", name, ErrExist, err)
+		}
+
+		if tt.folder {
+			if _, err := os.Lstat(filepath.Join(p, "sub")); !os.IsNotExist(err) {
+				t.Errorf("%s: expected folder to be kept, got %v", name, err)
+			}
+		} else if b, err := os.ReadFile(p); err != nil || string(b) != "new" {
+			t.Errorf("%s: expected %q, got %q %v", name, "new", string(b), err)
+		}
+
+		if _, err := os.Lstat(item.File()); err != nil {
+			t.Errorf("%s: expected item to stay in the trash, got %v", name, err)
+		}
+		if _, err := os.Lstat(item.infoFile()); err != nil {
+			t.Errorf("%s: expected info file to stay, got %v", name, err)
+		}
+	}
+}
+
+func TestTrashVolume(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	top := filepath.Join(dir, "volume")
+
+	// a pretend volume in place of the real mount points
+	saved := trashMounts
+	trashMounts = func() []string { return []string{top} }
+	t.Cleanup(func() { trashMounts = saved })
+
+	uid := strconv.Itoa(os.Getuid())
+
+	tests := map[string]struct {
+		shared   os.FileMode
+		expected string
+	}{
+		"private": {
+			expected: filepath.Join(top, ".Trash-"+uid),
+		},
+		"shared sticky": {
+			shared:   0777 | os.ModeSticky,
+			expected: filepath.Join(top, ".Trash", uid),
+		},
+		"shared not sticky": {
+			shared:   0777,
+			expected: filepath.Join(top, ".Trash-"+uid),
+		},
+	}
+
+	for name, tt := range tests {
+		if err := os.RemoveAll(top); err != nil {
+			t.Fatal(err)
+		}
+		if err := MkDir(filepath.Join(top, "docs")); err != nil {
+			t.Fatal(err)
+		}
+
+		if tt.shared != 0 {
+			shared := filepath.Join(top, ".Trash")
+			if err := os.Mkdir(shared, 0777); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(shared, tt.shared); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		p := filepath.Join(top, "docs", "plan.txt")
+		if err := os.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		trash, err := volumeTrash(top)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if trash.dir != tt.expected {
+			t.Errorf("%s: expected %v, got %v", name, tt.expected, trash.dir)
+		}
+
+		item, err := trashTo(p, trash)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// paths in a volume trash are relative to the top of the volume
+		b, err := os.ReadFile(item.infoFile())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), "\nPath=docs/plan.txt\n") {
+			t.Errorf("%s: expected relative path, got %q", name, string(b))
+		}
+
+		items, err := ListTrash()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(items) != 1 || items[0].Path != p || items[0].Trash != tt.expected {
+			t.Fatalf("%s: expected %v in %v, got %v", name, p, tt.expected, items)
+		}
+
+		if err := RestoreFromTrash(items[0]); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if b, err := os.ReadFile(p); err != nil || string(b) != name {
+			t.Errorf("%s: expected %v, got %q %v", name, name, string(b), err)
+		}
+	}
+}
+
+func TestEmptyTrash(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	top := filepath.Join(dir, "volume")
+
+	saved := trashMounts
+	trashMounts = func() []string { return []string{top} }
+	t.Cleanup(func() { trashMounts = saved })
+
+	if err := MkDir(top); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{filepath.Join(dir, "a.txt"), filepath.Join(top, "b.txt")} {
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Trash(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	trash, err := volumeTrash(top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trashTo(filepath.Join(top, "b.txt"), trash); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := ListTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %v", items)
+	}
+
+	if err := EmptyTrash(); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err = ListTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Errorf("expected empty trash, got %v", items)
+	}
+
+	for _, d := range []string{filepath.Join(dir, "data", "Trash", "files"), filepath.Join(trash.dir, "info")} {
+		entries, err := os.ReadDir(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("%s: expected empty folder, got %v", d, entries)
+		}
+	}
+}
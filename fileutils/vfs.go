@@ -0,0 +1,310 @@
+package fileutils
+
+import (
+	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// File is an open file on an FS.
+type File interface {
+	fs.File
+	io.Writer
+	io.Seeker
+}
+
+// WriterFS holds the mutating operations of an FS. Names follow the io/fs
+// rules, slash separated and unrooted.
+type WriterFS interface {
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Mkdir(name string, perm fs.FileMode) error
+	MkdirAll(name string, perm fs.FileMode) error
+	Remove(name string) error
+	RemoveAll(name string) error
+	Rename(oldname, newname string) error
+	Chmod(name string, mode fs.FileMode) error
+}
+
+// LinkFS holds the symlink operations of an FS.
+type LinkFS interface {
+	Lstat(name string) (fs.FileInfo, error)
+	Symlink(oldname, newname string) error
+	Readlink(name string) (string, error)
+}
+
+// FS is a writable io/fs filesystem. NewOSFS, NewMemFS, ReadOnly and
+// CopyOnWrite provide implementations, and the ...FS variants of the
+// package helpers work against any of them.
+type FS interface {
+	fs.StatFS
+	fs.ReadDirFS
+	WriterFS
+	LinkFS
+}
+
+func FindFS(fsys fs.FS, folderPath, ext string) ([]string, error) {
+	var funcName string = "FindFS"
+
+	var files []string
+
+	fi, err := fs.Stat(fsys, folderPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []string{}, newError(funcName, folderPath, "", ErrNotExist)
+	}
+	if err != nil {
+		return []string{}, newError(funcName, folderPath, "error checking file info", err)
+	}
+
+	if !fi.IsDir() {
+		return []string{}, newError(funcName, folderPath, "", ErrNotFolder)
+	}
+
+	if lfs, ok := fsys.(LinkFS); ok {
+		sym, err := isSymlinkFS(lfs, folderPath)
+		if err != nil {
+			return []string{}, newError(funcName, folderPath, "error checking symlink", err)
+		}
+		if sym {
+			return []string{}, newError(funcName, folderPath, "", ErrIsSymlink)
+		}
+	}
+
+	err = fs.WalkDir(fsys, folderPath, func(s string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+		if path.Ext(d.Name()) == ext {
+			files = append(files, s)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return []string{}, newError(funcName, folderPath, "error walking target", err)
+	}
+
+	return files, nil
+}
+
+func FoldersFS(fsys fs.FS, folderPath string) ([]string, error) {
+	var funcName string = "FoldersFS"
+
+	fi, err := fs.Stat(fsys, folderPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []string{}, newError(funcName, folderPath, "", ErrNotExist)
+	}
+	if err != nil {
+		return []string{}, newError(funcName, folderPath, "error checking file info", err)
+	}
+
+	if !fi.IsDir() {
+		return []string{}, newError(funcName, folderPath, "", ErrNotFolder)
+	}
+
+	var folders []string
+
+	err = fs.WalkDir(fsys, folderPath, func(s string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+		if d.IsDir() && s != folderPath {
+			folders = append(folders, s)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return []string{}, newError(funcName, folderPath, "error walking target", err)
+	}
+
+	return folders, nil
+}
+
+func EmptyFolderFS(fsys FS, folderPath string) error {
+	var funcName string = "EmptyFolderFS"
+
+	if !FolderExistsFS(fsys, folderPath) {
+		return newError(funcName, folderPath, "", ErrNotExist)
+	}
+
+	dir, err := fsys.ReadDir(folderPath)
+	if err != nil {
+		return newError(funcName, folderPath, "error reading target", err)
+	}
+
+	// carry on past failures so as much as possible is removed
+	var firstErr error
+	for _, d := range dir {
+		target := path.Join(folderPath, d.Name())
+		if err := fsys.RemoveAll(target); err != nil && firstErr == nil {
+			firstErr = newError(funcName, target, "error removing target", err)
+		}
+	}
+
+	return firstErr
+}
+
+func IsFileFS(fsys fs.FS, fileName string) bool {
+	i, err := fs.Stat(fsys, fileName)
+	if err != nil {
+		return false
+	}
+	return !i.IsDir()
+}
+
+func IsFolderFS(fsys fs.FS, folderPath string) bool {
+	i, err := fs.Stat(fsys, folderPath)
+	if err != nil {
+		return false
+	}
+	return i.IsDir()
+}
+
+func FolderExistsFS(fsys fs.FS, folderPath string) bool {
+	return FileExistsFS(fsys, folderPath)
+}
+
+func FileExistsFS(fsys fs.FS, fileName string) bool {
+	if _, err := fs.Stat(fsys, fileName); errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	return true
+}
+
+func IsSymlinkFS(fsys FS, fileName string) (bool, error) {
+	var funcName string = "IsSymlinkFS"
+
+	sym, err := isSymlinkFS(fsys, fileName)
+	if err != nil {
+		return false, newError(funcName, fileName, "error checking file info", err)
+	}
+
+	return sym, nil
+}
+
+func isSymlinkFS(fsys LinkFS, fileName string) (bool, error) {
+	fi, err := fsys.Lstat(fileName)
+	if err != nil {
+		return false, err
+	}
+
+	return fi.Mode()&fs.ModeSymlink == fs.ModeSymlink, nil
+}
+
+func MkDirFS(fsys FS, dir string) error {
+	var funcName string = "MkDirFS"
+
+	var sym bool
+	var err error
+
+	if FileExistsFS(fsys, dir) {
+		sym, err = isSymlinkFS(fsys, dir)
+		if err != nil {
+			return newError(funcName, dir, "error checking if symlink", err)
+		}
+	}
+
+	if !sym {
+		if err := fsys.MkdirAll(dir, fs.ModePerm); err != nil {
+			return newError(funcName, dir, "error creating folder", err)
+		}
+	}
+
+	return nil
+}
+
+func MkFileFS(fsys FS, fileName string) error {
+	var funcName string = "MkFileFS"
+
+	if FileExistsFS(fsys, fileName) {
+		return newError(funcName, fileName, "", ErrExist)
+	}
+
+	emptyFile, err := fsys.OpenFile(fileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return newError(funcName, fileName, "error creating file", err)
+	}
+	emptyFile.Close()
+
+	return nil
+}
+
+func GetFileFS(fsys FS, fileName string) (File, error) {
+	var funcName string = "GetFileFS"
+
+	f, err := fsys.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0655)
+	if err != nil {
+		return nil, newError(funcName, fileName, "error opening file", err)
+	}
+
+	return f, nil
+}
+
+func WriteFileFS(fsys FS, fileName string, fileContent string) error {
+	var funcName string = "WriteFileFS"
+
+	f, err := GetFileFS(fsys, fileName)
+	if err != nil {
+		return newError(funcName, fileName, "error preparing to write file", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write([]byte(fileContent))
+	if err != nil {
+		return newError(funcName, fileName, "error writing file", err)
+	}
+
+	return nil
+}
+
+func GetMD5HashFS(fsys fs.FS, filePath string) (string, error) {
+	var funcName string = "GetMD5HashFS"
+
+	file, err := fsys.Open(filePath)
+	if err != nil {
+		return "", newError(funcName, filePath, "error opening file", err)
+	}
+	defer file.Close()
+
+	hash := md5.New() //nolint:gosec
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", newError(funcName, filePath, "error hash file", err)
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+func FileSizeBytesFS(fsys fs.FS, filePath string) (int64, error) {
+	var funcName string = "FileSizeBytesFS"
+
+	stat, err := fs.Stat(fsys, filePath)
+	if err != nil {
+		return 0, newError(funcName, filePath, "error getting file info", err)
+	}
+
+	return stat.Size(), nil
+}
+
+func FileHashFS(fsys fs.FS, fileName string) (string, error) {
+	var funcName string = "FileHashFS"
+
+	f, err := fsys.Open(fileName)
+	if err != nil {
+		return "", newError(funcName, fileName, "error opening file", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", newError(funcName, fileName, "error hashing file", err)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,541 @@
+package fileutils
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// MemFS is an in memory FS. Permissions are recorded but not enforced.
+type MemFS struct {
+	mu   sync.RWMutex
+	root *memNode
+}
+
+type memNode struct {
+	mode     fs.FileMode
+	modTime  time.Time
+	data     []byte
+	target   string
+	children map[string]*memNode
+}
+
+func NewMemFS() *MemFS {
+	return &MemFS{
+		root: &memNode{
+			mode:     fs.ModeDir | 0755,
+			modTime:  time.Now(),
+			children: map[string]*memNode{},
+		},
+	}
+}
+
+func (n *memNode) info(name string) fs.FileInfo {
+	return &memInfo{
+		name:    name,
+		size:    int64(len(n.data)),
+		mode:    n.mode,
+		modTime: n.modTime,
+	}
+}
+
+// lookup resolves name and returns the folder holding it, its base name and
+// the node itself, which is nil when it does not exist yet. The folder is
+// nil when name is the root.
+func (m *MemFS) lookup(op, name string, followLast bool) (*memNode, string, *memNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	stack := []*memNode{m.root}
+	parts := splitPath(name)
+
+	var hops int
+	for len(parts) > 0 {
+		comp := parts[0]
+		parts = parts[1:]
+		last := len(parts) == 0
+		top := stack[len(stack)-1]
+
+		if comp == ".." {
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+
+		child := top.children[comp]
+		if last && (child == nil || !followLast || child.mode&fs.ModeSymlink == 0) {
+			return top, comp, child, nil
+		}
+
+		if child == nil {
+			return nil, "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+
+		if child.mode&fs.ModeSymlink != 0 {
+			hops++
+			if hops > maxSymlinkHops {
+				return nil, "", nil, &fs.PathError{Op: op, Path: name, Err: syscall.ELOOP}
+			}
+			if strings.HasPrefix(child.target, "/") {
+				stack = stack[:1]
+			}
+			parts = append(splitPath(child.target), parts...)
+			continue
+		}
+
+		if !child.mode.IsDir() {
+			return nil, "", nil, &fs.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+		}
+
+		stack = append(stack, child)
+	}
+
+	// resolved to a folder with nothing left, which may be the root
+	if len(stack) == 1 {
+		return nil, ".", m.root, nil
+	}
+
+	parent := stack[len(stack)-2]
+	for base, n := range parent.children {
+		if n == stack[len(stack)-1] {
+			return parent, base, n, nil
+		}
+	}
+
+	return nil, "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *MemFS) Open(name string) (fs.File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, base, n, err := m.lookup("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+
+	switch {
+	case n == nil && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case n == nil:
+		n = &memNode{mode: perm & fs.ModePerm, modTime: time.Now()}
+		dir.children[base] = n
+	case flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case n.mode.IsDir() && writable:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	}
+
+	if flag&os.O_TRUNC != 0 && writable {
+		n.data = nil
+		n.modTime = time.Now()
+	}
+
+	return &memFile{fs: m, node: n, name: name, flag: flag}, nil
+}
+
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	return m.stat("stat", name, true)
+}
+
+func (m *MemFS) Lstat(name string) (fs.FileInfo, error) {
+	return m.stat("lstat", name, false)
+}
+
+func (m *MemFS) stat(op, name string, follow bool) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, _, n, err := m.lookup(op, name, follow)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return n.info(path.Base(name)), nil
+}
+
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, _, n, err := m.lookup("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !n.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+	}
+
+	return n.entries(), nil
+}
+
+func (n *memNode) entries() []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(n.children))
+	for base, c := range n.children {
+		entries = append(entries, fs.FileInfoToDirEntry(c.info(base)))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries
+}
+
+func (m *MemFS) Mkdir(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, base, n, err := m.lookup("mkdir", name, false)
+	if err != nil {
+		return err
+	}
+	if n != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+
+	dir.children[base] = &memNode{
+		mode:     fs.ModeDir | perm&fs.ModePerm,
+		modTime:  time.Now(),
+		children: map[string]*memNode{},
+	}
+
+	return nil
+}
+
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	parts := splitPath(name)
+	for i := range parts {
+		p := strings.Join(parts[:i+1], "/")
+
+		fi, err := m.Stat(p)
+		if err == nil {
+			if !fi.IsDir() {
+				return &fs.PathError{Op: "mkdir", Path: p, Err: syscall.ENOTDIR}
+			}
+			continue
+		}
+
+		if err := m.Mkdir(p, perm); err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, base, n, err := m.lookup("remove", name, false)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if dir == nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	if n.mode.IsDir() && len(n.children) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+	}
+
+	delete(dir.children, base)
+
+	return nil
+}
+
+func (m *MemFS) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, base, n, err := m.lookup("remove", name, false)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if n == nil {
+		return nil
+	}
+	if dir == nil {
+		n.children = map[string]*memNode{}
+		return nil
+	}
+
+	delete(dir.children, base)
+
+	return nil
+}
+
+func (m *MemFS) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	odir, obase, on, err := m.lookup("rename", oldname, false)
+	if err != nil {
+		return err
+	}
+	if on == nil || odir == nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrNotExist}
+	}
+
+	ndir, nbase, nn, err := m.lookup("rename", newname, false)
+	if err != nil {
+		return err
+	}
+	if ndir == nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrInvalid}
+	}
+	if on.mode.IsDir() && (newname == oldname || strings.HasPrefix(newname, oldname+"/")) {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrInvalid}
+	}
+	if nn != nil && nn.mode.IsDir() && len(nn.children) > 0 {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: syscall.ENOTEMPTY}
+	}
+
+	delete(odir.children, obase)
+	ndir.children[nbase] = on
+
+	return nil
+}
+
+// Symlink creates newname pointing at oldname. Relative targets are resolved
+// from the folder holding the link, absolute ones from the root of the FS.
+func (m *MemFS) Symlink(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, base, n, err := m.lookup("symlink", newname, false)
+	if err != nil {
+		return err
+	}
+	if n != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+
+	dir.children[base] = &memNode{
+		mode:    fs.ModeSymlink | fs.ModePerm,
+		modTime: time.Now(),
+		target:  oldname,
+	}
+
+	return nil
+}
+
+func (m *MemFS) Readlink(name string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, _, n, err := m.lookup("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if n == nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrNotExist}
+	}
+	if n.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return n.target, nil
+}
+
+func (m *MemFS) Chmod(name string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, _, n, err := m.lookup("chmod", name, true)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrNotExist}
+	}
+
+	n.mode = n.mode&^fs.ModePerm | mode&fs.ModePerm
+
+	return nil
+}
+
+type memFile struct {
+	fs     *MemFS
+	node   *memNode
+	name   string
+	flag   int
+	offset int64
+	dirPos int
+	closed bool
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	f.fs.mu.RLock()
+	defer f.fs.mu.RUnlock()
+
+	if f.closed {
+		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fs.ErrClosed}
+	}
+
+	return f.node.info(path.Base(f.name)), nil
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	switch {
+	case f.closed:
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrClosed}
+	case f.node.mode.IsDir():
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: syscall.EISDIR}
+	case f.flag&os.O_WRONLY != 0:
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: syscall.EBADF}
+	}
+
+	if f.offset >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.node.data[f.offset:])
+	f.offset += int64(n)
+
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	switch {
+	case f.closed:
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrClosed}
+	case f.flag&(os.O_WRONLY|os.O_RDWR) == 0:
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: syscall.EBADF}
+	}
+
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.node.data))
+	}
+
+	end := f.offset + int64(len(p))
+	if end > int64(len(f.node.data)) {
+		data := make([]byte, end)
+		copy(data, f.node.data)
+		f.node.data = data
+	}
+
+	copy(f.node.data[f.offset:], p)
+	f.offset = end
+	f.node.modTime = time.Now()
+
+	return len(p), nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrClosed}
+	}
+
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	}
+
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	f.offset = offset
+
+	return offset, nil
+}
+
+// ReadDir makes folders opened from a MemFS satisfy fs.ReadDirFile.
+func (f *memFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: fs.ErrClosed}
+	}
+	if !f.node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: syscall.ENOTDIR}
+	}
+
+	entries := f.node.entries()
+	if f.dirPos > len(entries) {
+		f.dirPos = len(entries)
+	}
+	entries = entries[f.dirPos:]
+
+	if n > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+		if len(entries) > n {
+			entries = entries[:n]
+		}
+	}
+
+	f.dirPos += len(entries)
+
+	return entries, nil
+}
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+
+	return nil
+}
+
+type memInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *memInfo) Name() string       { return i.name }
+func (i *memInfo) Size() int64        { return i.size }
+func (i *memInfo) Mode() fs.FileMode  { return i.mode }
+func (i *memInfo) ModTime() time.Time { return i.modTime }
+func (i *memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *memInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,156 @@
+package fileutils
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// OSFS is an FS backed by a folder on disk.
+type OSFS struct {
+	dir string
+}
+
+func NewOSFS(dir string) *OSFS {
+	return &OSFS{dir: dir}
+}
+
+func (o *OSFS) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	return filepath.Join(o.dir, filepath.FromSlash(name)), nil
+}
+
+func (o *OSFS) Open(name string) (fs.File, error) {
+	p, err := o.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (o *OSFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	p, err := o.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(p, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (o *OSFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := o.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Stat(p)
+}
+
+func (o *OSFS) Lstat(name string) (fs.FileInfo, error) {
+	p, err := o.path("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Lstat(p)
+}
+
+func (o *OSFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := o.path("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadDir(p)
+}
+
+func (o *OSFS) Mkdir(name string, perm fs.FileMode) error {
+	p, err := o.path("mkdir", name)
+	if err != nil {
+		return err
+	}
+
+	return os.Mkdir(p, perm)
+}
+
+func (o *OSFS) MkdirAll(name string, perm fs.FileMode) error {
+	p, err := o.path("mkdir", name)
+	if err != nil {
+		return err
+	}
+
+	return os.MkdirAll(p, perm)
+}
+
+func (o *OSFS) Remove(name string) error {
+	p, err := o.path("remove", name)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(p)
+}
+
+func (o *OSFS) RemoveAll(name string) error {
+	p, err := o.path("remove", name)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(p)
+}
+
+func (o *OSFS) Rename(oldname, newname string) error {
+	op, err := o.path("rename", oldname)
+	if err != nil {
+		return err
+	}
+	np, err := o.path("rename", newname)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(op, np)
+}
+
+// Symlink creates newname pointing at oldname, which is stored as given.
+func (o *OSFS) Symlink(oldname, newname string) error {
+	p, err := o.path("symlink", newname)
+	if err != nil {
+		return err
+	}
+
+	return os.Symlink(oldname, p)
+}
+
+func (o *OSFS) Readlink(name string) (string, error) {
+	p, err := o.path("readlink", name)
+	if err != nil {
+		return "", err
+	}
+
+	return os.Readlink(p)
+}
+
+func (o *OSFS) Chmod(name string, mode fs.FileMode) error {
+	p, err := o.path("chmod", name)
+	if err != nil {
+		return err
+	}
+
+	return os.Chmod(p, mode)
+}
@@ -0,0 +1,579 @@
+package fileutils
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const writeFlags = os.O_WRONLY | os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_APPEND
+
+type readOnlyFS struct {
+	FS
+}
+
+// ReadOnly wraps fsys so every write fails with fs.ErrPermission.
+func ReadOnly(fsys FS) FS {
+	return &readOnlyFS{FS: fsys}
+}
+
+func (r *readOnlyFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	if flag&writeFlags != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+
+	return r.FS.OpenFile(name, flag, perm)
+}
+
+func (r *readOnlyFS) Mkdir(name string, perm fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrPermission}
+}
+
+func (r *readOnlyFS) MkdirAll(name string, perm fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrPermission}
+}
+
+func (r *readOnlyFS) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+}
+
+func (r *readOnlyFS) RemoveAll(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+}
+
+func (r *readOnlyFS) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrPermission}
+}
+
+func (r *readOnlyFS) Symlink(oldname, newname string) error {
+	return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: fs.ErrPermission}
+}
+
+func (r *readOnlyFS) Chmod(name string, mode fs.FileMode) error {
+	return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrPermission}
+}
+
+type cowFS struct {
+	base    FS
+	overlay FS
+
+	mu sync.Mutex
+	// paths removed from base
+	deleted map[string]bool
+	// paths recreated in overlay that hide whatever base has underneath
+	opaque map[string]bool
+}
+
+// CopyOnWrite layers overlay on top of base. Reads see overlay first, then
+// base. Writes only ever touch overlay, copying a file up from base before
+// it is changed, and removals of base entries are remembered in memory.
+// Symlinks are resolved within the layer holding them.
+func CopyOnWrite(base, overlay FS) FS {
+	return &cowFS{
+		base:    base,
+		overlay: overlay,
+		deleted: map[string]bool{},
+		opaque:  map[string]bool{},
+	}
+}
+
+// baseVisible reports whether name in base has not been removed or hidden.
+func (c *cowFS) baseVisible(name string) bool {
+	if name == "." {
+		return true
+	}
+
+	parts := strings.Split(name, "/")
+	for i := range parts {
+		p := strings.Join(parts[:i+1], "/")
+		if c.deleted[p] {
+			return false
+		}
+		if i < len(parts)-1 && c.opaque[p] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// layer returns the layer name should be read from.
+func (c *cowFS) layer(op, name string) (FS, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	if _, err := c.overlay.Lstat(name); err == nil {
+		return c.overlay, nil
+	}
+
+	if c.baseVisible(name) {
+		if _, err := c.base.Lstat(name); err == nil {
+			return c.base, nil
+		}
+	}
+
+	return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+func (c *cowFS) inBase(name string) bool {
+	if !c.baseVisible(name) {
+		return false
+	}
+
+	_, err := c.base.Lstat(name)
+
+	return err == nil
+}
+
+// recreated is called when name is created in overlay.
+func (c *cowFS) recreated(name string) {
+	if c.deleted[name] {
+		delete(c.deleted, name)
+		c.opaque[name] = true
+	}
+}
+
+func (c *cowFS) Open(name string) (fs.File, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, err := c.layer("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := l.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi, err := f.Stat(); err == nil && fi.IsDir() {
+		return &cowDir{File: f, c: c, name: name}, nil
+	}
+
+	return f, nil
+}
+
+func (c *cowFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	if flag&writeFlags == 0 {
+		f, err := c.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		if file, ok := f.(File); ok {
+			return file, nil
+		}
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if err := c.copyUp(name); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := c.copyUpParents(name); err != nil {
+		return nil, err
+	}
+
+	f, err := c.overlay.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	c.recreated(name)
+
+	return f, nil
+}
+
+func (c *cowFS) Stat(name string) (fs.FileInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, err := c.layer("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.Stat(name)
+}
+
+func (c *cowFS) Lstat(name string) (fs.FileInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, err := c.layer("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.Lstat(name)
+}
+
+func (c *cowFS) Readlink(name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, err := c.layer("readlink", name)
+	if err != nil {
+		return "", err
+	}
+
+	return l.Readlink(name)
+}
+
+func (c *cowFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.readDir(name)
+}
+
+func (c *cowFS) readDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	merged := map[string]fs.DirEntry{}
+	var found bool
+	var lastErr error
+
+	over, err := c.overlay.ReadDir(name)
+	if err == nil {
+		found = true
+		for _, e := range over {
+			merged[e.Name()] = e
+		}
+	} else if !os.IsNotExist(err) {
+		lastErr = err
+	}
+
+	if c.baseVisible(name) && !c.opaque[name] {
+		under, err := c.base.ReadDir(name)
+		if err == nil {
+			found = true
+			for _, e := range under {
+				if _, ok := merged[e.Name()]; ok || c.deleted[path.Join(name, e.Name())] {
+					continue
+				}
+				merged[e.Name()] = e
+			}
+		} else if !os.IsNotExist(err) {
+			lastErr = err
+		}
+	}
+
+	if !found {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(merged))
+	for _, e := range merged {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+func (c *cowFS) Mkdir(name string, perm fs.FileMode) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.layer("mkdir", name); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+
+	if err := c.copyUpParents(name); err != nil {
+		return err
+	}
+
+	if err := c.overlay.Mkdir(name, perm); err != nil {
+		return err
+	}
+	c.recreated(name)
+
+	return nil
+}
+
+func (c *cowFS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	parts := splitPath(name)
+	for i := range parts {
+		p := strings.Join(parts[:i+1], "/")
+
+		fi, err := c.Stat(p)
+		if err == nil {
+			if !fi.IsDir() {
+				return &fs.PathError{Op: "mkdir", Path: p, Err: syscall.ENOTDIR}
+			}
+			continue
+		}
+
+		if err := c.Mkdir(p, perm); err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *cowFS) Remove(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, err := c.layer("remove", name)
+	if err != nil {
+		return err
+	}
+
+	if fi, err := l.Lstat(name); err == nil && fi.IsDir() {
+		entries, err := c.readDir(name)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+		}
+	}
+
+	if err := c.overlay.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if c.inBase(name) {
+		c.deleted[name] = true
+	}
+
+	return nil
+}
+
+func (c *cowFS) RemoveAll(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if err := c.overlay.RemoveAll(name); err != nil {
+		return err
+	}
+
+	if c.inBase(name) {
+		c.deleted[name] = true
+	}
+
+	return nil
+}
+
+func (c *cowFS) Rename(oldname, newname string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.layer("rename", oldname); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrNotExist}
+	}
+	if !fs.ValidPath(newname) {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrInvalid}
+	}
+
+	if err := c.copyUpTree(oldname); err != nil {
+		return err
+	}
+	if err := c.copyUpParents(newname); err != nil {
+		return err
+	}
+
+	if err := c.overlay.Rename(oldname, newname); err != nil {
+		return err
+	}
+
+	if c.inBase(oldname) {
+		c.deleted[oldname] = true
+	}
+	delete(c.deleted, newname)
+	if c.inBase(newname) {
+		c.opaque[newname] = true
+	}
+
+	return nil
+}
+
+func (c *cowFS) Symlink(oldname, newname string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.layer("symlink", newname); err == nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+
+	if err := c.copyUpParents(newname); err != nil {
+		return err
+	}
+
+	if err := c.overlay.Symlink(oldname, newname); err != nil {
+		return err
+	}
+	c.recreated(newname)
+
+	return nil
+}
+
+func (c *cowFS) Chmod(name string, mode fs.FileMode) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.layer("chmod", name); err != nil {
+		return err
+	}
+
+	if err := c.copyUp(name); err != nil {
+		return err
+	}
+
+	return c.overlay.Chmod(name, mode)
+}
+
+// copyUp copies a single entry from base into overlay, folders are created
+// empty. It is a no-op when overlay already has name.
+func (c *cowFS) copyUp(name string) error {
+	if _, err := c.overlay.Lstat(name); err == nil {
+		return nil
+	}
+
+	if !c.inBase(name) {
+		return &fs.PathError{Op: "copyup", Path: name, Err: fs.ErrNotExist}
+	}
+
+	if err := c.copyUpParents(name); err != nil {
+		return err
+	}
+
+	fi, err := c.base.Lstat(name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fi.IsDir():
+		return c.overlay.Mkdir(name, fi.Mode().Perm())
+
+	case fi.Mode()&fs.ModeSymlink != 0:
+		target, err := c.base.Readlink(name)
+		if err != nil {
+			return err
+		}
+		return c.overlay.Symlink(target, name)
+	}
+
+	src, err := c.base.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := c.overlay.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
+
+func (c *cowFS) copyUpParents(name string) error {
+	dir := path.Dir(name)
+	if dir == "." {
+		return nil
+	}
+
+	if _, err := c.overlay.Lstat(dir); err == nil {
+		return nil
+	}
+
+	if err := c.copyUp(dir); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (c *cowFS) copyUpTree(name string) error {
+	if err := c.copyUp(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	fi, err := c.overlay.Lstat(name)
+	if err != nil || !fi.IsDir() {
+		return nil //nolint:nilerr
+	}
+
+	entries, err := c.readDir(name)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if err := c.copyUpTree(path.Join(name, e.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cowDir merges base entries into ReadDir on folders opened from overlay.
+type cowDir struct {
+	File
+	c       *cowFS
+	name    string
+	entries []fs.DirEntry
+	loaded  bool
+}
+
+func (d *cowDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.loaded {
+		entries, err := d.c.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.loaded = true
+	}
+
+	entries := d.entries
+	if n > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+		if len(entries) > n {
+			entries = entries[:n]
+		}
+	}
+	d.entries = d.entries[len(entries):]
+
+	return entries, nil
+}
@@ -0,0 +1,485 @@
+package fileutils
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func testFileSystems(t *testing.T) map[string]func() FS {
+	t.Helper()
+
+	return map[string]func() FS{
+		"os": func() FS {
+			return NewOSFS(t.TempDir())
+		},
+		"mem": func() FS {
+			return NewMemFS()
+		},
+		"copy on write": func() FS {
+			return CopyOnWrite(NewMemFS(), NewMemFS())
+		},
+	}
+}
+
+func readFS(t *testing.T, fsys fs.FS, name string) string {
+	t.Helper()
+
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestFSOperations(t *testing.T) {
+	for name, newFS := range testFileSystems(t) {
+		fsys := newFS()
+
+		err := fsys.MkdirAll("a/b/c", 0755)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		err = WriteFileFS(fsys, "a/b/file.txt", "hello")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		err = WriteFileFS(fsys, "a/b/file.txt", " world")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if actual := readFS(t, fsys, "a/b/file.txt"); actual != "hello world" {
+			t.Errorf("%s: expected appended content, got %q", name, actual)
+		}
+
+		f, err := fsys.OpenFile("a/b/file.txt", os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := f.Seek(6, io.SeekStart); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := f.Write([]byte("WORLD")); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		f.Close()
+
+		if actual := readFS(t, fsys, "a/b/file.txt"); actual != "hello WORLD" {
+			t.Errorf("%s: expected overwritten content, got %q", name, actual)
+		}
+
+		_, err = fsys.OpenFile("a/b/file.txt", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if !errors.Is(err, fs.ErrExist) {
+			t.Errorf("%s: expected ErrExist, got %v", name, err)
+		}
+
+		err = fsys.Symlink("b/file.txt", "a/link.txt")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		target, err := fsys.Readlink("a/link.txt")
+		if err != nil || target != "b/file.txt" {
+			t.Errorf("%s: expected link target b/file.txt, got %q %v", name, target, err)
+		}
+
+		if actual := readFS(t, fsys, "a/link.txt"); actual != "hello WORLD" {
+			t.Errorf("%s: expected to read through link, got %q", name, actual)
+		}
+
+		sym, err := IsSymlinkFS(fsys, "a/link.txt")
+		if err != nil || !sym {
+			t.Errorf("%s: expected a/link.txt to be a symlink", name)
+		}
+
+		err = fsys.Chmod("a/b/file.txt", 0600)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		fi, err := fsys.Stat("a/b/file.txt")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if fi.Mode().Perm() != 0600 || fi.Size() != 11 || fi.Name() != "file.txt" {
+			t.Errorf("%s: unexpected file info %v %v %v", name, fi.Mode(), fi.Size(), fi.Name())
+		}
+
+		err = fsys.Rename("a/b/file.txt", "a/b/c/moved.txt")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if FileExistsFS(fsys, "a/b/file.txt") || !IsFileFS(fsys, "a/b/c/moved.txt") {
+			t.Errorf("%s: expected file to be moved", name)
+		}
+
+		err = fsys.Remove("a/b")
+		if err == nil {
+			t.Errorf("%s: expected error removing non empty folder", name)
+		}
+
+		entries, err := fsys.ReadDir("a")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		if !reflect.DeepEqual([]string{"b", "link.txt"}, names) {
+			t.Errorf("%s: unexpected entries %v", name, names)
+		}
+
+		err = fsys.RemoveAll("a")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if FolderExistsFS(fsys, "a") {
+			t.Errorf("%s: expected a to be removed", name)
+		}
+
+		_, err = fsys.Stat("../escape")
+		if !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid for invalid path, got %v", name, err)
+		}
+	}
+}
+
+func TestFSConformance(t *testing.T) {
+	for name, newFS := range testFileSystems(t) {
+		fsys := newFS()
+
+		files := map[string]string{
+			"a.txt":         "a",
+			"dir/b.txt":     "bb",
+			"dir/sub/c.txt": "ccc",
+		}
+		for fileName, content := range files {
+			if err := fsys.MkdirAll(dirOf(fileName), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := WriteFileFS(fsys, fileName, content); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := fstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c.txt")
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func dirOf(fileName string) string {
+	for i := len(fileName) - 1; i >= 0; i-- {
+		if fileName[i] == '/' {
+			return fileName[:i]
+		}
+	}
+
+	return "."
+}
+
+func TestReadOnlyFS(t *testing.T) {
+	mem := NewMemFS()
+
+	err := WriteFileFS(mem, "file.txt", "content")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := ReadOnly(mem)
+
+	if actual := readFS(t, fsys, "file.txt"); actual != "content" {
+		t.Errorf("expected content, got %q", actual)
+	}
+
+	tests := map[string]func() error{
+		"write": func() error {
+			return WriteFileFS(fsys, "file.txt", "more")
+		},
+		"mkdir": func() error {
+			return fsys.MkdirAll("dir", 0755)
+		},
+		"remove": func() error {
+			return fsys.Remove("file.txt")
+		},
+		"rename": func() error {
+			return fsys.Rename("file.txt", "other.txt")
+		},
+		"symlink": func() error {
+			return fsys.Symlink("file.txt", "link.txt")
+		},
+		"chmod": func() error {
+			return fsys.Chmod("file.txt", 0600)
+		},
+	}
+
+	for name, fn := range tests {
+		if err := fn(); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+
+	if actual := readFS(t, mem, "file.txt"); actual != "content" {
+		t.Errorf("expected underlying file to be untouched, got %q", actual)
+	}
+}
+
+func TestCopyOnWriteFS(t *testing.T) {
+	base := NewOSFS(t.TempDir())
+
+	for fileName, content := range map[string]string{
+		"keep.txt":        "keep",
+		"change.txt":      "original",
+		"gone.txt":        "gone",
+		"dir/nested.txt":  "nested",
+		"dir2/nested.txt": "nested",
+	} {
+		if err := base.MkdirAll(dirOf(fileName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFileFS(base, fileName, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	overlay := NewMemFS()
+	fsys := ReadOnly(base)
+	cow := CopyOnWrite(fsys, overlay)
+
+	err := WriteFileFS(cow, "change.txt", " changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cow.Remove("gone.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cow.RemoveAll("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cow.MkdirAll("dir", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cow.Rename("dir2", "renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := readFS(t, cow, "change.txt"); actual != "original changed" {
+		t.Errorf("expected copied up content, got %q", actual)
+	}
+
+	if actual := readFS(t, base, "change.txt"); actual != "original" {
+		t.Errorf("expected base to be untouched, got %q", actual)
+	}
+
+	if actual := readFS(t, cow, "renamed/nested.txt"); actual != "nested" {
+		t.Errorf("expected renamed folder content, got %q", actual)
+	}
+
+	tests := map[string]bool{
+		"keep.txt":        true,
+		"gone.txt":        false,
+		"dir":             true,
+		"dir/nested.txt":  false,
+		"dir2":            false,
+		"dir2/nested.txt": false,
+	}
+
+	for fileName, expected := range tests {
+		if actual := FileExistsFS(cow, fileName); expected != actual {
+			t.Errorf("%s: expected exists %v, got %v", fileName, expected, actual)
+		}
+		if !FileExistsFS(base, fileName) {
+			t.Errorf("%s: expected base to still have it", fileName)
+		}
+	}
+
+	files, err := FindFS(cow, ".", ".txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"change.txt", "keep.txt", "renamed/nested.txt"}
+	if !reflect.DeepEqual(expected, files) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+}
+
+func TestFSHelpers(t *testing.T) {
+	fsys := NewMemFS()
+
+	err := MkDirFS(fsys, "testfolder/sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fileName := range []string{"testfolder/0.txt", "testfolder/sub/1.txt", "testfolder/2.csv"} {
+		if err := MkFileFS(fsys, fileName); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = MkFileFS(fsys, "testfolder/0.txt")
+	if err == nil {
+		t.Errorf("expected error creating existing file, got nil")
+	}
+
+	files, err := FindFS(fsys, "testfolder", ".txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]string{"testfolder/0.txt", "testfolder/sub/1.txt"}, files) {
+		t.Errorf("unexpected files %v", files)
+	}
+
+	folders, err := FoldersFS(fsys, "testfolder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]string{"testfolder/sub"}, folders) {
+		t.Errorf("unexpected folders %v", folders)
+	}
+
+	hash, err := FileHashFS(fsys, "testfolder/0.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("unexpected hash %v", hash)
+	}
+
+	md5, err := GetMD5HashFS(fsys, "testfolder/0.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md5 != "d41d8cd98f00b204e9800998ecf8427e" {
+		t.Errorf("unexpected md5 %v", md5)
+	}
+
+	err = WriteFileFS(fsys, "testfolder/2.csv", "a,b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := FileSizeBytesFS(fsys, "testfolder/2.csv")
+	if err != nil || size != 3 {
+		t.Errorf("expected size 3, got %v %v", size, err)
+	}
+
+	err = fsys.Symlink("sub", "testfolder/link")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = FindFS(fsys, "testfolder/link", ".txt")
+	if err == nil {
+		t.Errorf("expected error finding in symlinked folder, got nil")
+	}
+
+	err = EmptyFolderFS(fsys, "testfolder")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := fsys.ReadDir("testfolder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected testfolder to be empty, got %v", entries)
+	}
+}
+
+// failFS fails every Open with err.
+type failFS struct {
+	err error
+}
+
+func (f failFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: f.err}
+}
+
+func TestFSHelpersStatErrors(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		notExist bool
+	}{
+		"missing":    {err: fs.ErrNotExist, notExist: true},
+		"permission": {err: fs.ErrPermission},
+	}
+
+	for name, tt := range tests {
+		fsys := failFS{err: tt.err}
+
+		_, findErr := FindFS(fsys, "dir", ".txt")
+		_, foldersErr := FoldersFS(fsys, "dir")
+
+		for fn, err := range map[string]error{"FindFS": findErr, "FoldersFS": foldersErr} {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: %s: expected %v, got %v", name, fn, tt.err, err)
+			}
+			if errors.Is(err, ErrNotExist) != tt.notExist {
+				t.Errorf("%s: %s: expected ErrNotExist %v, got %v", name, fn, tt.notExist, err)
+			}
+		}
+	}
+}
+
+// failRemoveFS fails RemoveAll for one name.
+type failRemoveFS struct {
+	FS
+	name string
+}
+
+func (f failRemoveFS) RemoveAll(name string) error {
+	if name == f.name {
+		return &fs.PathError{Op: "removeall", Path: name, Err: fs.ErrPermission}
+	}
+
+	return f.FS.RemoveAll(name)
+}
+
+func TestEmptyFolderFSCarriesOn(t *testing.T) {
+	mem := NewMemFS()
+	if err := MkDirFS(mem, "dir"); err != nil {
+		t.Fatal(err)
+	}
+	for _, fileName := range []string{"dir/a", "dir/b", "dir/c"} {
+		if err := MkFileFS(mem, fileName); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := EmptyFolderFS(failRemoveFS{FS: mem, name: "dir/a"}, "dir")
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected %v, got %v", fs.ErrPermission, err)
+	}
+
+	entries, err := mem.ReadDir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "a" {
+		t.Errorf("expected only the failed entry to be left, got %v", entries)
+	}
+}
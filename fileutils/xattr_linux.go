@@ -0,0 +1,252 @@
+package fileutils
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// initial buffer size for xattr reads, grown on ERANGE.
+const xattrBufSize = 256
+
+func GetXattr(fileName, attr string) ([]byte, error) {
+	var funcName string = "GetXattr"
+
+	v, err := getxattr(fileName, attr, true)
+	if err != nil {
+		return nil, newTargetError(funcName, fileName, attr, "error reading xattr", err)
+	}
+
+	return v, nil
+}
+
+func LGetXattr(fileName, attr string) ([]byte, error) {
+	var funcName string = "LGetXattr"
+
+	v, err := getxattr(fileName, attr, false)
+	if err != nil {
+		return nil, newTargetError(funcName, fileName, attr, "error reading xattr", err)
+	}
+
+	return v, nil
+}
+
+func SetXattr(fileName, attr string, value []byte) error {
+	var funcName string = "SetXattr"
+
+	if err := setxattr(fileName, attr, value, true); err != nil {
+		return newTargetError(funcName, fileName, attr, "error writing xattr", err)
+	}
+
+	return nil
+}
+
+func LSetXattr(fileName, attr string, value []byte) error {
+	var funcName string = "LSetXattr"
+
+	if err := setxattr(fileName, attr, value, false); err != nil {
+		return newTargetError(funcName, fileName, attr, "error writing xattr", err)
+	}
+
+	return nil
+}
+
+func ListXattr(fileName string) ([]string, error) {
+	var funcName string = "ListXattr"
+
+	names, err := listxattr(fileName, true)
+	if err != nil {
+		return []string{}, newError(funcName, fileName, "error listing xattrs", err)
+	}
+
+	return names, nil
+}
+
+func LListXattr(fileName string) ([]string, error) {
+	var funcName string = "LListXattr"
+
+	names, err := listxattr(fileName, false)
+	if err != nil {
+		return []string{}, newError(funcName, fileName, "error listing xattrs", err)
+	}
+
+	return names, nil
+}
+
+func RemoveXattr(fileName, attr string) error {
+	var funcName string = "RemoveXattr"
+
+	if err := removexattr(fileName, attr, true); err != nil {
+		return newTargetError(funcName, fileName, attr, "error removing xattr", err)
+	}
+
+	return nil
+}
+
+func LRemoveXattr(fileName, attr string) error {
+	var funcName string = "LRemoveXattr"
+
+	if err := removexattr(fileName, attr, false); err != nil {
+		return newTargetError(funcName, fileName, attr, "error removing xattr", err)
+	}
+
+	return nil
+}
+
+// CopyXattrs copies every extended attribute of src onto dst. Neither path is
+// followed if it is a symlink.
+func CopyXattrs(src, dst string) error {
+	var funcName string = "CopyXattrs"
+
+	names, err := listxattr(src, false)
+	if err != nil {
+		return newError(funcName, src, "error listing xattrs", err)
+	}
+
+	for _, name := range names {
+		v, err := getxattr(src, name, false)
+		if err != nil {
+			return newTargetError(funcName, src, name, "error reading xattr", err)
+		}
+		if err := setxattr(dst, name, v, false); err != nil {
+			return newTargetError(funcName, dst, name, "error writing xattr", err)
+		}
+	}
+
+	return nil
+}
+
+func getxattr(fileName, attr string, follow bool) ([]byte, error) {
+	trap := uintptr(syscall.SYS_GETXATTR)
+	if !follow {
+		trap = syscall.SYS_LGETXATTR
+	}
+
+	buf := make([]byte, xattrBufSize)
+	for {
+		n, err := xattrCall(trap, fileName, attr, buf)
+		if errors.Is(err, syscall.ERANGE) {
+			// the value grew between calls, ask for its size and retry
+			n, err = xattrCall(trap, fileName, attr, nil)
+			if err != nil {
+				return nil, err
+			}
+			buf = make([]byte, n)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return buf[:n], nil
+	}
+}
+
+func setxattr(fileName, attr string, value []byte, follow bool) error {
+	trap := uintptr(syscall.SYS_SETXATTR)
+	if !follow {
+		trap = syscall.SYS_LSETXATTR
+	}
+
+	p, err := syscall.BytePtrFromString(fileName)
+	if err != nil {
+		return err
+	}
+	a, err := syscall.BytePtrFromString(attr)
+	if err != nil {
+		return err
+	}
+
+	var v unsafe.Pointer
+	if len(value) > 0 {
+		v = unsafe.Pointer(&value[0])
+	}
+
+	_, _, errno := syscall.Syscall6(trap, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(a)), uintptr(v), uintptr(len(value)), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func listxattr(fileName string, follow bool) ([]string, error) {
+	trap := uintptr(syscall.SYS_LISTXATTR)
+	if !follow {
+		trap = syscall.SYS_LLISTXATTR
+	}
+
+	p, err := syscall.BytePtrFromString(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, xattrBufSize)
+	for {
+		r, _, errno := syscall.Syscall(trap, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+		if errno == syscall.ERANGE {
+			buf = make([]byte, len(buf)*2)
+			continue
+		}
+		if errno != 0 {
+			return nil, errno
+		}
+
+		// names come back as a sequence of NUL terminated strings
+		names := []string{}
+		for _, name := range strings.Split(string(buf[:r]), "\x00") {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+
+		return names, nil
+	}
+}
+
+func removexattr(fileName, attr string, follow bool) error {
+	trap := uintptr(syscall.SYS_REMOVEXATTR)
+	if !follow {
+		trap = syscall.SYS_LREMOVEXATTR
+	}
+
+	p, err := syscall.BytePtrFromString(fileName)
+	if err != nil {
+		return err
+	}
+	a, err := syscall.BytePtrFromString(attr)
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall(trap, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(a)), 0)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func xattrCall(trap uintptr, fileName, attr string, buf []byte) (int, error) {
+	p, err := syscall.BytePtrFromString(fileName)
+	if err != nil {
+		return 0, err
+	}
+	a, err := syscall.BytePtrFromString(attr)
+	if err != nil {
+		return 0, err
+	}
+
+	var b unsafe.Pointer
+	if len(buf) > 0 {
+		b = unsafe.Pointer(&buf[0])
+	}
+
+	r, _, errno := syscall.Syscall6(trap, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(a)), uintptr(b), uintptr(len(buf)), 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+
+	return int(r), nil
+}
@@ -0,0 +1,182 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"testing/fstest"
+)
+
+// skipUnlessXattrs skips the test when the filesystem holding fileName does
+// not support user xattrs.
+func skipUnlessXattrs(t *testing.T, fileName string) {
+	t.Helper()
+
+	err := setxattr(fileName, "user.probe", []byte("1"), true)
+	if errors.Is(err, syscall.ENOTSUP) {
+		t.Skip("filesystem does not support user xattrs")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = removexattr(fileName, "user.probe", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestXattr(t *testing.T) {
+	targetFile := filepath.Join(testTree(t, fstest.MapFS{"xattr.txt": {}}), "xattr.txt")
+	skipUnlessXattrs(t, targetFile)
+
+	tests := map[string]struct {
+		attr  string
+		value []byte
+	}{
+		"checksum": {
+			attr:  "user.checksum",
+			value: []byte("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"),
+		},
+		"stage": {
+			attr:  "user.stage",
+			value: []byte("processed"),
+		},
+		"empty": {
+			attr:  "user.empty",
+			value: []byte{},
+		},
+	}
+
+	for name, tt := range tests {
+		err := SetXattr(targetFile, tt.attr, tt.value)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		actual, err := GetXattr(targetFile, tt.attr)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if !reflect.DeepEqual(tt.value, actual) {
+			t.Errorf("%s: expected %q, got %q", name, tt.value, actual)
+		}
+	}
+
+	names, err := ListXattr(targetFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != len(tests) {
+		t.Errorf("expected %v xattrs, got %v", len(tests), names)
+	}
+
+	for _, tt := range tests {
+		err := RemoveXattr(targetFile, tt.attr)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = getxattr(targetFile, "user.stage", true)
+	if !errors.Is(err, syscall.ENODATA) {
+		t.Errorf("expected ENODATA after removal, got %v", err)
+	}
+}
+
+func TestXattrLargeValue(t *testing.T) {
+	targetFile := filepath.Join(testTree(t, fstest.MapFS{"xattr.txt": {}}), "xattr.txt")
+	skipUnlessXattrs(t, targetFile)
+
+	value := make([]byte, xattrBufSize*4)
+	for i := range value {
+		value[i] = byte(i)
+	}
+
+	err := SetXattr(targetFile, "user.large", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := GetXattr(targetFile, "user.large")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(value, actual) {
+		t.Errorf("expected %v bytes back, got %v", len(value), len(actual))
+	}
+}
+
+func TestLXattrSymlink(t *testing.T) {
+	targetFile := filepath.Join(testTree(t, fstest.MapFS{"xattr.txt": {}}), "xattr.txt")
+	skipUnlessXattrs(t, targetFile)
+	link := filepath.Join(filepath.Dir(targetFile), "link.txt")
+
+	err := os.Symlink(targetFile, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// user xattrs are not permitted on symlinks themselves
+	err = LSetXattr(link, "user.stage", []byte("raw"))
+	if err == nil {
+		t.Errorf("expected error setting user xattr on symlink")
+	}
+
+	err = SetXattr(link, "user.stage", []byte("raw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := GetXattr(targetFile, "user.stage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(actual) != "raw" {
+		t.Errorf("expected xattr to be set through the link, got %q", actual)
+	}
+
+	names, err := LListXattr(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range names {
+		if n == "user.stage" {
+			t.Errorf("expected LListXattr not to follow the link")
+		}
+	}
+}
+
+func TestCopyXattrs(t *testing.T) {
+	dir := testTree(t, fstest.MapFS{"xattr.txt": {}, "copy.txt": {}})
+	src := filepath.Join(dir, "xattr.txt")
+	dst := filepath.Join(dir, "copy.txt")
+	skipUnlessXattrs(t, src)
+
+	err := SetXattr(src, "user.checksum", []byte("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = CopyXattrs(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := GetXattr(dst, "user.checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(actual) != "abc" {
+		t.Errorf("expected copied xattr abc, got %q", actual)
+	}
+}
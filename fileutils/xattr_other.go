@@ -0,0 +1,43 @@
+//go:build !linux
+
+package fileutils
+
+import (
+	"runtime"
+)
+
+func GetXattr(fileName, attr string) ([]byte, error) {
+	return nil, newError("GetXattr", fileName, "xattrs not supported on "+runtime.GOOS, ErrUnsupported)
+}
+
+func LGetXattr(fileName, attr string) ([]byte, error) {
+	return nil, newError("LGetXattr", fileName, "xattrs not supported on "+runtime.GOOS, ErrUnsupported)
+}
+
+func SetXattr(fileName, attr string, value []byte) error {
+	return newError("SetXattr", fileName, "xattrs not supported on "+runtime.GOOS, ErrUnsupported)
+}
+
+func LSetXattr(fileName, attr string, value []byte) error {
+	return newError("LSetXattr", fileName, "xattrs not supported on "+runtime.GOOS, ErrUnsupported)
+}
+
+func ListXattr(fileName string) ([]string, error) {
+	return []string{}, newError("ListXattr", fileName, "xattrs not supported on "+runtime.GOOS, ErrUnsupported)
+}
+
+func LListXattr(fileName string) ([]string, error) {
+	return []string{}, newError("LListXattr", fileName, "xattrs not supported on "+runtime.GOOS, ErrUnsupported)
+}
+
+func RemoveXattr(fileName, attr string) error {
+	return newError("RemoveXattr", fileName, "xattrs not supported on "+runtime.GOOS, ErrUnsupported)
+}
+
+func LRemoveXattr(fileName, attr string) error {
+	return newError("LRemoveXattr", fileName, "xattrs not supported on "+runtime.GOOS, ErrUnsupported)
+}
+
+func CopyXattrs(src, dst string) error {
+	return newError("CopyXattrs", src, "xattrs not supported on "+runtime.GOOS, ErrUnsupported)
+}
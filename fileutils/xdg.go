@@ -0,0 +1,255 @@
+package fileutils
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// folders created for XDG base directories are private, as the spec asks
+const xdgDirPerm = 0700
+
+// XDGConfigHome returns $XDG_CONFIG_HOME, defaulting to ~/.config. As with
+// the other XDG variables, a relative path is ignored.
+func XDGConfigHome() (string, error) {
+	return xdgHome("XDGConfigHome", "XDG_CONFIG_HOME", ".config")
+}
+
+// XDGCacheHome returns $XDG_CACHE_HOME, defaulting to ~/.cache.
+func XDGCacheHome() (string, error) {
+	return xdgHome("XDGCacheHome", "XDG_CACHE_HOME", ".cache")
+}
+
+// XDGDataHome returns $XDG_DATA_HOME, defaulting to ~/.local/share.
+func XDGDataHome() (string, error) {
+	return xdgHome("XDGDataHome", "XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// XDGStateHome returns $XDG_STATE_HOME, defaulting to ~/.local/state.
+func XDGStateHome() (string, error) {
+	return xdgHome("XDGStateHome", "XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// XDGRuntimeDir returns $XDG_RUNTIME_DIR. The spec has no default, so when it
+// is not set a private folder in os.TempDir is created and used instead. As
+// that path is predictable it is only used when it is a real folder owned by
+// the current user with mode 0700, anything else someone could have planted
+// there gives an error.
+func XDGRuntimeDir() (string, error) {
+	var funcName string = "XDGRuntimeDir"
+
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+
+	dir := filepath.Join(os.TempDir(), "xdg-runtime-"+strconv.Itoa(os.Getuid()))
+	if err := MkDirMode(dir, xdgDirPerm); err != nil {
+		return "", err
+	}
+
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return "", newError(funcName, dir, "error checking file info", err)
+	}
+
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return "", newError(funcName, dir, "insecure runtime folder", ErrIsSymlink)
+	}
+	if !fi.IsDir() {
+		return "", newError(funcName, dir, "insecure runtime folder", ErrNotFolder)
+	}
+	if uid, ok := fileOwner(fi); ok && uid != os.Getuid() {
+		return "", newError(funcName, dir, "insecure runtime folder, not owned by current user", ErrInvalid)
+	}
+	if fi.Mode().Perm() != xdgDirPerm {
+		return "", newError(funcName, dir, "insecure runtime folder, mode "+fi.Mode().Perm().String(), ErrInvalid)
+	}
+
+	return dir, nil
+}
+
+// XDGConfigDirs returns $XDG_CONFIG_DIRS, defaulting to /etc/xdg, most
+// important first.
+func XDGConfigDirs() []string {
+	return xdgDirs("XDG_CONFIG_DIRS", "/etc/xdg")
+}
+
+// XDGDataDirs returns $XDG_DATA_DIRS, defaulting to /usr/local/share and
+// /usr/share, most important first.
+func XDGDataDirs() []string {
+	return xdgDirs("XDG_DATA_DIRS", "/usr/local/share:/usr/share")
+}
+
+// AppDirs resolves the XDG base directories of one application, each being
+// the matching base directory with the application name appended. Folders
+// are created on demand, readable only by the user.
+type AppDirs struct {
+	Name string
+}
+
+func NewAppDirs(name string) AppDirs {
+	return AppDirs{Name: name}
+}
+
+func (a AppDirs) Config() (string, error) {
+	return a.dir("AppDirs.Config", XDGConfigHome)
+}
+
+func (a AppDirs) Cache() (string, error) {
+	return a.dir("AppDirs.Cache", XDGCacheHome)
+}
+
+func (a AppDirs) Data() (string, error) {
+	return a.dir("AppDirs.Data", XDGDataHome)
+}
+
+func (a AppDirs) State() (string, error) {
+	return a.dir("AppDirs.State", XDGStateHome)
+}
+
+func (a AppDirs) Runtime() (string, error) {
+	return a.dir("AppDirs.Runtime", XDGRuntimeDir)
+}
+
+// ConfigPaths returns where the config file name may be, in precedence
+// order, the user's config home first and then each of XDGConfigDirs.
+func (a AppDirs) ConfigPaths(name string) ([]string, error) {
+	return a.searchPaths("AppDirs.ConfigPaths", name, XDGConfigHome, XDGConfigDirs)
+}
+
+// DataPaths is ConfigPaths for data files.
+func (a AppDirs) DataPaths(name string) ([]string, error) {
+	return a.searchPaths("AppDirs.DataPaths", name, XDGDataHome, XDGDataDirs)
+}
+
+// FindConfig returns the first of ConfigPaths that exists, or an error
+// matching ErrNotExist when none do.
+func (a AppDirs) FindConfig(name string) (string, error) {
+	var funcName string = "AppDirs.FindConfig"
+
+	paths, err := a.ConfigPaths(name)
+	if err != nil {
+		return "", err
+	}
+
+	for _, p := range paths {
+		if FileExists(p) {
+			return p, nil
+		}
+	}
+
+	return "", newTargetError(funcName, name, a.Name, "", ErrNotExist)
+}
+
+// FindData is FindConfig for data files.
+func (a AppDirs) FindData(name string) (string, error) {
+	var funcName string = "AppDirs.FindData"
+
+	paths, err := a.DataPaths(name)
+	if err != nil {
+		return "", err
+	}
+
+	for _, p := range paths {
+		if FileExists(p) {
+			return p, nil
+		}
+	}
+
+	return "", newTargetError(funcName, name, a.Name, "", ErrNotExist)
+}
+
+func (a AppDirs) dir(funcName string, base func() (string, error)) (string, error) {
+	if a.Name == "" || a.Name == "." || a.Name == ".." || strings.ContainsRune(a.Name, filepath.Separator) {
+		return "", newError(funcName, a.Name, "invalid application name", ErrInvalid)
+	}
+
+	b, err := base()
+	if err != nil {
+		return "", newError(funcName, a.Name, "error resolving base folder", err)
+	}
+
+	dir := filepath.Join(b, a.Name)
+	if err := MkDirMode(dir, xdgDirPerm); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+func (a AppDirs) searchPaths(funcName, name string, home func() (string, error), dirs func() []string) ([]string, error) {
+	h, err := home()
+	if err != nil {
+		return nil, newError(funcName, a.Name, "error resolving base folder", err)
+	}
+
+	paths := []string{filepath.Join(h, a.Name, name)}
+	for _, d := range dirs() {
+		paths = append(paths, filepath.Join(d, a.Name, name))
+	}
+
+	return paths, nil
+}
+
+// ExpandPath expands a leading ~ or ~user to a home folder, and $VAR or
+// ${VAR} to the value of the environment variable, empty when unset.
+func ExpandPath(p string) (string, error) {
+	var funcName string = "ExpandPath"
+
+	if strings.HasPrefix(p, "~") {
+		name, rest := p[1:], ""
+		if i := strings.IndexAny(name, `/\`); i >= 0 {
+			name, rest = name[:i], name[i:]
+		}
+
+		var home string
+		if name == "" {
+			h, err := os.UserHomeDir()
+			if err != nil {
+				return "", newError(funcName, p, "error finding home folder", err)
+			}
+			home = h
+		} else {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", newTargetError(funcName, p, name, "error looking up user", err)
+			}
+			home = u.HomeDir
+		}
+
+		p = home + rest
+	}
+
+	return os.ExpandEnv(p), nil
+}
+
+func xdgHome(funcName, env, def string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", newTargetError(funcName, def, env, "error finding home folder", err)
+	}
+
+	return filepath.Join(home, def), nil
+}
+
+func xdgDirs(env, def string) []string {
+	value := os.Getenv(env)
+	if value == "" {
+		value = def
+	}
+
+	var dirs []string
+	for _, d := range filepath.SplitList(value) {
+		if filepath.IsAbs(d) {
+			dirs = append(dirs, d)
+		}
+	}
+
+	return dirs
+}
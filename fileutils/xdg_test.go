@@ -0,0 +1,354 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestXDGHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := map[string]struct {
+		env      string
+		value    string
+		fn       func() (string, error)
+		expected string
+	}{
+		"config default": {
+			env:      "XDG_CONFIG_HOME",
+			fn:       XDGConfigHome,
+			expected: filepath.Join(home, ".config"),
+		},
+		"config set": {
+			env:      "XDG_CONFIG_HOME",
+			value:    "/opt/config",
+			fn:       XDGConfigHome,
+			expected: "/opt/config",
+		},
+		"config relative ignored": {
+			env:      "XDG_CONFIG_HOME",
+			value:    "config",
+			fn:       XDGConfigHome,
+			expected: filepath.Join(home, ".config"),
+		},
+		"cache default": {
+			env:      "XDG_CACHE_HOME",
+			fn:       XDGCacheHome,
+			expected: filepath.Join(home, ".cache"),
+		},
+		"data default": {
+			env:      "XDG_DATA_HOME",
+			fn:       XDGDataHome,
+			expected: filepath.Join(home, ".local", "share"),
+		},
+		"state default": {
+			env:      "XDG_STATE_HOME",
+			fn:       XDGStateHome,
+			expected: filepath.Join(home, ".local", "state"),
+		},
+		"runtime set": {
+			env:      "XDG_RUNTIME_DIR",
+			value:    "/run/user/1000",
+			fn:       XDGRuntimeDir,
+			expected: "/run/user/1000",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Setenv(tt.env, tt.value)
+
+		actual, err := tt.fn()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if actual != tt.expected {
+			t.Errorf("%s: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestXDGDirs(t *testing.T) {
+	tests := map[string]struct {
+		value    string
+		expected []string
+	}{
+		"default": {
+			expected: []string{"/usr/local/share", "/usr/share"},
+		},
+		"set": {
+			value:    "/opt/share:/usr/share",
+			expected: []string{"/opt/share", "/usr/share"},
+		},
+		"relative entries dropped": {
+			value:    "share:/usr/share:",
+			expected: []string{"/usr/share"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Setenv("XDG_DATA_DIRS", tt.value)
+
+		actual := XDGDataDirs()
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+
+	t.Setenv("XDG_CONFIG_DIRS", "")
+	if actual := XDGConfigDirs(); !reflect.DeepEqual(actual, []string{"/etc/xdg"}) {
+		t.Errorf("config default: expected %v, got %v", []string{"/etc/xdg"}, actual)
+	}
+}
+
+func TestAppDirs(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(base, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(base, "cache"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(base, "data"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(base, "state"))
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(base, "runtime"))
+
+	app := NewAppDirs("myapp")
+
+	tests := map[string]struct {
+		fn       func() (string, error)
+		expected string
+	}{
+		"config":  {fn: app.Config, expected: filepath.Join(base, "config", "myapp")},
+		"cache":   {fn: app.Cache, expected: filepath.Join(base, "cache", "myapp")},
+		"data":    {fn: app.Data, expected: filepath.Join(base, "data", "myapp")},
+		"state":   {fn: app.State, expected: filepath.Join(base, "state", "myapp")},
+		"runtime": {fn: app.Runtime, expected: filepath.Join(base, "runtime", "myapp")},
+	}
+
+	for name, tt := range tests {
+		actual, err := tt.fn()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if actual != tt.expected {
+			t.Errorf("%s: expected %v, got %v", name, tt.expected, actual)
+		}
+
+		fi, err := os.Stat(actual)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if fi.Mode().Perm() != 0700 {
+			t.Errorf("%s: expected mode %v, got %v", name, os.FileMode(0700), fi.Mode().Perm())
+		}
+	}
+
+	if _, err := NewAppDirs("").Config(); !errors.Is(err, ErrInvalid) {
+		t.Errorf("empty name: expected %v, got %v", ErrInvalid, err)
+	}
+	if _, err := NewAppDirs("a/b").Config(); !errors.Is(err, ErrInvalid) {
+		t.Errorf("nested name: expected %v, got %v", ErrInvalid, err)
+	}
+	for _, name := range []string{".", ".."} {
+		if _, err := NewAppDirs(name).Config(); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q: expected %v, got %v", name, ErrInvalid, err)
+		}
+	}
+}
+
+func TestXDGRuntimeDirFallback(t *testing.T) {
+	tests := map[string]struct {
+		setup    func(dir string) error
+		expected error
+	}{
+		"created": {
+			setup: func(dir string) error { return nil },
+		},
+		"existing private folder": {
+			setup: func(dir string) error { return os.Mkdir(dir, 0700) },
+		},
+		"open folder": {
+			setup: func(dir string) error {
+				if err := os.Mkdir(dir, 0755); err != nil {
+					return err
+				}
+				return os.Chmod(dir, 0755)
+			},
+			expected: ErrInvalid,
+		},
+		"symlink": {
+			setup: func(dir string) error {
+				target := dir + "-target"
+				if err := os.Mkdir(target, 0700); err != nil {
+					return err
+				}
+				if err := os.Chmod(target, 0777); err != nil {
+					return err
+				}
+				return os.Symlink(target, dir)
+			},
+			expected: ErrIsSymlink,
+		},
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", "")
+
+	for name, tt := range tests {
+		tmp := t.TempDir()
+		t.Setenv("TMPDIR", tmp)
+		dir := filepath.Join(tmp, "xdg-runtime-"+strconv.Itoa(os.Getuid()))
+
+		if err := tt.setup(dir); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		actual, err := XDGRuntimeDir()
+		if tt.expected != nil {
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("%s: expected %v, got %v", name, tt.expected, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if actual != dir {
+			t.Errorf("%s: expected %v, got %v", name, dir, actual)
+		}
+	}
+}
+
+func TestAppDirsFindConfig(t *testing.T) {
+	base := t.TempDir()
+	home := filepath.Join(base, "home")
+	system1 := filepath.Join(base, "etc1")
+	system2 := filepath.Join(base, "etc2")
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("XDG_CONFIG_DIRS", system1+string(os.PathListSeparator)+system2)
+
+	app := NewAppDirs("myapp")
+
+	paths, err := app.ConfigPaths("config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedPaths := []string{
+		filepath.Join(home, "myapp", "config.json"),
+		filepath.Join(system1, "myapp", "config.json"),
+		filepath.Join(system2, "myapp", "config.json"),
+	}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("paths: expected %v, got %v", expectedPaths, paths)
+	}
+
+	if _, err := app.FindConfig("config.json"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("none: expected %v, got %v", ErrNotExist, err)
+	}
+
+	// the most important file present wins
+	for _, p := range expectedPaths[1:] {
+		if err := MkDir(filepath.Dir(p)); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actual, err := app.FindConfig("config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != expectedPaths[1] {
+		t.Errorf("system: expected %v, got %v", expectedPaths[1], actual)
+	}
+
+	if err := MkDir(filepath.Dir(expectedPaths[0])); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(expectedPaths[0], []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err = app.FindConfig("config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != expectedPaths[0] {
+		t.Errorf("home: expected %v, got %v", expectedPaths[0], actual)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APP_ROOT", "/srv/app")
+	t.Setenv("EMPTY_VAR", "")
+
+	tests := map[string]struct {
+		path     string
+		expected string
+	}{
+		"tilde": {
+			path:     "~",
+			expected: home,
+		},
+		"tilde slash": {
+			path:     "~/.config/app",
+			expected: home + "/.config/app",
+		},
+		"variable": {
+			path:     "$APP_ROOT/data",
+			expected: "/srv/app/data",
+		},
+		"braced variable": {
+			path:     "${APP_ROOT}-old",
+			expected: "/srv/app-old",
+		},
+		"tilde and variable": {
+			path:     "~/$EMPTY_VAR/x",
+			expected: home + "//x",
+		},
+		"tilde not leading": {
+			path:     "/tmp/~",
+			expected: "/tmp/~",
+		},
+		"plain": {
+			path:     "relative/path",
+			expected: "relative/path",
+		},
+	}
+
+	for name, tt := range tests {
+		actual, err := ExpandPath(tt.path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if actual != tt.expected {
+			t.Errorf("%s: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+
+	if _, err := ExpandPath("~no-such-user-xyz/file"); err == nil {
+		t.Errorf("unknown user: expected error")
+	}
+}
+
+func TestMkDirMode(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+
+	if err := MkDirMode(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range []string{dir, filepath.Dir(dir)} {
+		fi, err := os.Stat(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0700 {
+			t.Errorf("%s: expected mode %v, got %v", d, os.FileMode(0700), fi.Mode().Perm())
+		}
+	}
+}